@@ -0,0 +1,189 @@
+package yamlformat
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+)
+
+type bigNumbers struct {
+	Int   *big.Int   `json:"int"`
+	Float *big.Float `json:"float"`
+	Rat   *big.Rat   `json:"rat"`
+	Value big.Int    `json:"value"`
+}
+
+func mustBigInt(t *testing.T, s string) *big.Int {
+	t.Helper()
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		t.Fatalf("invalid big.Int %q", s)
+	}
+	return v
+}
+
+func mustBigRat(t *testing.T, s string) *big.Rat {
+	t.Helper()
+	v, ok := new(big.Rat).SetString(s)
+	if !ok {
+		t.Fatalf("invalid big.Rat %q", s)
+	}
+	return v
+}
+
+func TestMarshalBigNumbers(t *testing.T) {
+	input := bigNumbers{
+		Int:   mustBigInt(t, "-123456789012345678901234567890"),
+		Float: new(big.Float).SetPrec(200).SetFloat64(1e25),
+		Rat:   mustBigRat(t, "12345678901234567890.0123456789"),
+		Value: *big.NewInt(7),
+	}
+
+	yamlBytes, err := Marshal(input)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	wantYAML := "int: -123456789012345678901234567890\n" +
+		"float: 10000000000000000905969664\n" +
+		"rat: 12345678901234567890.0123456789\n" +
+		"value: 7\n"
+	if string(yamlBytes) != wantYAML {
+		t.Errorf("Marshal() = %q, want %q", yamlBytes, wantYAML)
+	}
+
+	jsonBytes, err := MarshalJSONCompact(input)
+	if err != nil {
+		t.Fatalf("MarshalJSONCompact failed: %v", err)
+	}
+	wantJSON := `{"int":-123456789012345678901234567890,"float":10000000000000000905969664,"rat":12345678901234567890.0123456789,"value":7}`
+	if string(jsonBytes) != wantJSON {
+		t.Errorf("MarshalJSONCompact() = %q, want %q", jsonBytes, wantJSON)
+	}
+}
+
+func TestMarshalBigNumbersInInterface(t *testing.T) {
+	input := map[string]any{
+		"int":    mustBigInt(t, "12345678901234567890"),
+		"float":  big.NewFloat(1.5),
+		"rat":    *big.NewRat(3, 2),
+		"list":   []any{big.NewInt(1), "1"},
+		"struct": struct{ V any }{V: big.NewInt(2)},
+		"nil":    (*big.Int)(nil),
+	}
+	tests := []struct {
+		name    string
+		marshal func(v interface{}, opts ...yaml.EncodeOption) ([]byte, error)
+		want    string
+	}{
+		{
+			name:    "YAML",
+			marshal: Marshal,
+			want:    "float: 1.5\nint: 12345678901234567890\nlist:\n- 1\n- \"1\"\nnil: null\nrat: 1.5\nstruct:\n  v: 2\n",
+		},
+		{
+			name:    "JSON",
+			marshal: MarshalJSON,
+			want:    `{"float": 1.5, "int": 12345678901234567890, "list": [1, "1"], "nil": null, "rat": 1.5, "struct": {"v": 2}}` + "\n",
+		},
+		{
+			name:    "JSON compact",
+			marshal: MarshalJSONCompact,
+			want:    `{"float":1.5,"int":12345678901234567890,"list":[1,"1"],"nil":null,"rat":1.5,"struct":{"V":2}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.marshal(input)
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Marshal() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Marshal([]any{big.NewRat(1, 3)}); err == nil {
+		t.Error("Marshal() of 1/3 in interface{} succeeded, want error")
+	}
+	got, err := DefaultOptions().Without(DefaultBigNumbers).Marshal(map[string]any{"int": big.NewInt(5)})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if want := "int: \"5\"\n"; string(got) != want {
+		t.Errorf("Marshal() without DefaultBigNumbers = %q, want %q", got, want)
+	}
+}
+
+func TestMarshalBigRatInexact(t *testing.T) {
+	if _, err := Marshal(bigNumbers{Rat: big.NewRat(1, 3)}); err == nil {
+		t.Error("Marshal() of 1/3 succeeded, want error")
+	}
+}
+
+func TestUnmarshalBigNumbers(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{
+			name:  "YAML",
+			input: "int: 123456789012345678901234567890123\nfloat: 3.14159265358979323846264338327950288\nrat: 0.1\nvalue: 99999999999999999999\n",
+		},
+		{
+			name:  "JSON",
+			input: `{"int": 123456789012345678901234567890123, "float": 3.14159265358979323846264338327950288, "rat": 0.1, "value": 99999999999999999999}`,
+		},
+		{
+			name:  "quoted",
+			input: `{"int": "123456789012345678901234567890123", "float": "3.14159265358979323846264338327950288", "rat": "1/10", "value": "99999999999999999999"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got bigNumbers
+			if err := Unmarshal([]byte(tt.input), &got); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			if want := "123456789012345678901234567890123"; got.Int.String() != want {
+				t.Errorf("Int = %s, want %s", got.Int, want)
+			}
+			if want := "3.14159265358979323846264338327950288"; got.Float.Text('f', 35) != want {
+				t.Errorf("Float = %s, want %s", got.Float.Text('f', 35), want)
+			}
+			if want := big.NewRat(1, 10); got.Rat.Cmp(want) != 0 {
+				t.Errorf("Rat = %s, want %s", got.Rat, want)
+			}
+			if want := "99999999999999999999"; got.Value.String() != want {
+				t.Errorf("Value = %s, want %s", got.Value.String(), want)
+			}
+		})
+	}
+}
+
+func TestBigNumbersRoundTrip(t *testing.T) {
+	want := bigNumbers{
+		Int:   mustBigInt(t, "98765432109876543210987654321"),
+		Float: big.NewFloat(0.5),
+		Rat:   mustBigRat(t, "-0.000000000000000000001"),
+	}
+
+	for _, format := range []Format{FormatYAML, FormatJSON, FormatJSONL} {
+		t.Run(string(format), func(t *testing.T) {
+			data, err := format.Marshal(want)
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+			var got bigNumbers
+			if err := format.Unmarshal(data, &got); err != nil {
+				t.Fatalf("Unmarshal(%q) failed: %v", data, err)
+			}
+			if got.Int.Cmp(want.Int) != 0 || got.Float.Cmp(want.Float) != 0 || got.Rat.Cmp(want.Rat) != 0 {
+				t.Errorf("round trip of %q = %+v, want %+v", data, got, want)
+			}
+		})
+	}
+}
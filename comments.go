@@ -0,0 +1,260 @@
+package yamlformat
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// commentTag is the struct tag that declares the comment of a field, e.g. `yamlformat:"comment=Number of replicas"`
+const commentTag = "yamlformat"
+
+// Comments attaches comments to the values at paths in YAML output, e.g.
+//
+//	yamlformat.Comments(yaml.CommentMap{
+//		".spec.replicas": {yaml.HeadComment(" Number of replicas"), yaml.LineComment(" at least 2")},
+//	})
+//
+// Paths are like those of Document (".spec.items[0].name", or "." for the whole document),
+// so keys containing "." or "[" are quoted as JSON strings, like .labels."app.kubernetes.io/name".
+// The texts follow "#" as given, so they usually start with a space.
+// A later Comments option adds to earlier ones, replacing the comments of the same paths,
+// and replaces the comments that struct tags declare at the same positions.
+// Missing paths are ignored. JSON output drops the comments unless JSONC is set.
+// Use Comments rather than yaml.WithComment, which the package's own comments would replace.
+func Comments(comments yaml.CommentMap) yaml.EncodeOption {
+	normalized := make(yaml.CommentMap, len(comments))
+	for path, cs := range comments {
+		if yamlPath, ok := commentPath(path); ok {
+			normalized[yamlPath] = cs
+		}
+	}
+	return settingOption(func(p commentsProbe) {
+		if p.s.comments == nil {
+			p.s.comments = yaml.CommentMap{}
+		}
+		for path, cs := range normalized {
+			p.s.comments[path] = cs
+		}
+	})
+}
+
+// HeaderComment writes lines as a comment at the top of the output, such as "Generated by tool X; do not edit".
+// Each line is written after "# " (or "// " with JSONC), before any "---" marker.
+// A stream (NewEncoder, MarshalAll, NewStreamEncoder) gets the header once, before its first document.
+// JSON output drops it unless JSONC is set. A later HeaderComment replaces an earlier one.
+func HeaderComment(lines ...string) yaml.EncodeOption {
+	var split []string
+	for _, line := range lines {
+		split = append(split, strings.Split(line, "\n")...)
+	}
+	return settingOption(func(p headerProbe) { p.s.header = split })
+}
+
+// FooterComment writes lines as a comment at the end of the output, such as the command that generated it.
+// Each line is written after "# " (or "// " with JSONC). A stream gets the footer once, after its last document:
+// MarshalAll writes it at the end, and NewStreamEncoder when it is closed.
+// JSON output drops it unless JSONC is set. A later FooterComment replaces an earlier one.
+func FooterComment(lines ...string) yaml.EncodeOption {
+	var split []string
+	for _, line := range lines {
+		split = append(split, strings.Split(line, "\n")...)
+	}
+	return settingOption(func(p footerProbe) { p.s.footer = split })
+}
+
+// JSONC writes the comments of Comments, HeaderComment, FooterComment and struct tags into JSON output, making it
+// JSON with comments (JSONC): "//" comments in indented output, and "/* */" comments in single-line output.
+// It applies to MarshalJSON, MarshalJSONIndent, their encoders, FormatJSON and FormatJSONPretty.
+// JSON Lines and the encoding/json compatible MarshalJSONCompact are never commented.
+func JSONC() yaml.EncodeOption {
+	return jsonComments(true)
+}
+
+// jsonComments sets whether JSON output has comments
+func jsonComments(enabled bool) yaml.EncodeOption {
+	return settingOption(func(p jsoncProbe) { p.s.jsonc = enabled })
+}
+
+// withoutHeader drops the header comment, for the documents of a stream after the header is written
+func withoutHeader() yaml.EncodeOption {
+	return settingOption(func(p headerProbe) { p.s.header = nil })
+}
+
+// withoutFooter drops the footer comment, for the documents of a stream before the footer is written
+func withoutFooter() yaml.EncodeOption {
+	return settingOption(func(p footerProbe) { p.s.footer = nil })
+}
+
+// commentPath converts a Document path to a goccy/go-yaml path, which starts with "$" and quotes keys like $.'a.b'.
+// ok is false if goccy/go-yaml paths cannot address a key of path.
+func commentPath(path string) (yamlPath string, ok bool) {
+	steps, err := parseDocumentPath(path)
+	if err != nil {
+		// Let goccy/go-yaml report the invalid path
+		return "$" + strings.TrimPrefix(strings.TrimPrefix(path, "$"), "."), true
+	}
+	yamlPath = "$"
+	for _, step := range steps {
+		if step.isIndex {
+			yamlPath = fmt.Sprintf("%s[%d]", yamlPath, step.index)
+		} else if yamlPath, ok = childCommentPath(yamlPath, step.key); !ok {
+			return "", false
+		}
+	}
+	return yamlPath, true
+}
+
+// childCommentPath returns the goccy/go-yaml path of the value of key in the mapping at path.
+// Keys containing characters of the path syntax are quoted, like $.'a.b'.
+// ok is false for an empty key, and for a key that also contains "'" or a backslash,
+// as goccy/go-yaml does not read escapes in quoted keys reliably.
+func childCommentPath(path, key string) (childPath string, ok bool) {
+	switch {
+	case key == "":
+		return "", false
+	case !strings.ContainsAny(key, "$*.[]") && key[0] != '\'':
+		return path + "." + key, true
+	case strings.ContainsAny(key, `'\`):
+		return "", false
+	}
+	return path + ".'" + key + "'", true
+}
+
+// commentText returns lines as a comment with each line after marker (e.g. "#"), or "" if there are none
+func commentText(lines []string, marker string) string {
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(marker)
+		if line != "" {
+			b.WriteString(" " + line)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// documentPrefix returns what Marshal writes before a YAML document: the header comment and the "---" marker
+func (s encodeSettings) documentPrefix() string {
+	if s.documentStart {
+		return commentText(s.header, "#") + documentStartMarker
+	}
+	return commentText(s.header, "#")
+}
+
+// documentSuffix returns what Marshal writes after a YAML document: the footer comment
+func (s encodeSettings) documentSuffix() string {
+	return commentText(s.footer, "#")
+}
+
+// jsonHeader returns the header comment of JSON output, which is only written with JSONC
+func (s encodeSettings) jsonHeader() string {
+	if !s.jsonc {
+		return ""
+	}
+	return commentText(s.header, "//")
+}
+
+// jsonFooter returns the footer comment of JSON output, which is only written with JSONC
+func (s encodeSettings) jsonFooter() string {
+	if !s.jsonc {
+		return ""
+	}
+	return commentText(s.footer, "//")
+}
+
+// commentMap returns the comments for encoding v: those declared by the struct tags in v,
+// replaced by those of the Comments option at the same positions. v is invalid if unknown.
+func (s encodeSettings) commentMap(v reflect.Value) yaml.CommentMap {
+	cm := yaml.CommentMap{}
+	addTagComments(v, "$", cm)
+	for path, cs := range s.comments {
+		for _, c := range cm[path] {
+			if !slices.ContainsFunc(cs, func(o *yaml.Comment) bool { return o.Position == c.Position }) {
+				cs = append(cs[:len(cs):len(cs)], c)
+			}
+		}
+		cm[path] = cs
+	}
+	return cm
+}
+
+// addTagComments adds the comments declared by the struct tags in rv, the value at path, to cm
+func addTagComments(rv reflect.Value, path string, cm yaml.CommentMap) {
+	rv = encodedValue(rv)
+	switch {
+	case !rv.IsValid():
+	case rv.Type() == mapSliceType:
+		for _, item := range rv.Interface().(yaml.MapSlice) {
+			if itemPath, ok := childCommentPath(path, fmt.Sprint(item.Key)); ok {
+				addTagComments(reflect.ValueOf(item.Value), itemPath, cm)
+			}
+		}
+	case rv.Kind() == reflect.Struct:
+		fields := map[string]structField{}
+		structFields(rv, fields)
+		for name, f := range fields {
+			fieldPath, ok := childCommentPath(path, name)
+			if !ok {
+				continue
+			}
+			if text, ok := strings.CutPrefix(f.tag.Get(commentTag), "comment="); ok {
+				cm[fieldPath] = []*yaml.Comment{yaml.HeadComment(" " + text)}
+			}
+			addTagComments(f.value, fieldPath, cm)
+		}
+	case rv.Kind() == reflect.Map && mayHoldStructs(rv.Type().Elem()):
+		for iter := rv.MapRange(); iter.Next(); {
+			if valuePath, ok := childCommentPath(path, fmt.Sprint(iter.Key().Interface())); ok {
+				addTagComments(iter.Value(), valuePath, cm)
+			}
+		}
+	case (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && mayHoldStructs(rv.Type().Elem()):
+		for i := 0; i < rv.Len(); i++ {
+			addTagComments(rv.Index(i), fmt.Sprintf("%s[%d]", path, i), cm)
+		}
+	}
+}
+
+// mayHoldStructs reports whether values of t may be or contain structs
+func mayHoldStructs(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Struct, reflect.Interface, reflect.Pointer, reflect.Map, reflect.Slice, reflect.Array:
+		return true
+	}
+	return false
+}
+
+// detachRootComment returns cm without the head comments of the whole document, and those comments as text.
+// Reshaping needs this: reshape moves the comment lines above a key with the key, and the document's comment
+// is above the first key, which may be moved. The comments of other keys, including those of mappings in
+// sequences, move with their keys.
+func detachRootComment(cm yaml.CommentMap) (yaml.CommentMap, string) {
+	var b strings.Builder
+	var rest []*yaml.Comment
+	for _, c := range cm["$"] {
+		if c.Position != yaml.CommentHeadPosition {
+			rest = append(rest, c)
+			continue
+		}
+		for _, text := range c.Texts {
+			b.WriteString("#" + text + "\n")
+		}
+	}
+	if b.Len() == 0 {
+		return cm, ""
+	}
+	detached := make(yaml.CommentMap, len(cm))
+	for path, cs := range cm {
+		detached[path] = cs
+	}
+	if rest == nil {
+		delete(detached, "$")
+	} else {
+		detached["$"] = rest
+	}
+	return detached, b.String()
+}
@@ -0,0 +1,356 @@
+package yamlformat
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+	"github.com/google/go-cmp/cmp"
+)
+
+type commentTestConfig struct {
+	Name     string              `yaml:"name" yamlformat:"comment=replaced by Comments"`
+	Replicas int                 `yaml:"replicas" yamlformat:"comment=Number of replicas"`
+	Ports    []commentTestPort   `yaml:"ports"`
+	Labels   map[string]string   `yaml:"labels,omitempty" yamlformat:"comment=Extra labels"`
+	Limits   *commentTestLimits  `yaml:"limits,omitempty"`
+	Extra    map[string]struct{} `yaml:"-" yamlformat:"comment=never written"`
+}
+
+type commentTestPort struct {
+	Port     int    `yaml:"port"`
+	Protocol string `yaml:"protocol" yamlformat:"comment=TCP or UDP"`
+}
+
+type commentTestLimits struct {
+	CPU string `yaml:"cpu" yamlformat:"comment=In cores"`
+}
+
+var commentTestValue = commentTestConfig{
+	Name:     "web",
+	Replicas: 2,
+	Ports:    []commentTestPort{{Port: 80, Protocol: "TCP"}},
+	Limits:   &commentTestLimits{CPU: "500m"},
+}
+
+var commentTestOptions = []yaml.EncodeOption{
+	HeaderComment("Generated by tool X; do not edit"),
+	Comments(yaml.CommentMap{
+		".replicas": {yaml.LineComment(" at least 2")},
+		".name":     {yaml.HeadComment(" Name of the service, unique per cluster")},
+		"$.ports":   {yaml.FootComment(" end of ports")},
+		".missing":  {yaml.HeadComment(" ignored")},
+	}),
+}
+
+func TestComments(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		opts   []yaml.EncodeOption
+		want   string
+	}{
+		{
+			name:   "YAML",
+			format: FormatYAML,
+			want: `# Generated by tool X; do not edit
+# Name of the service, unique per cluster
+name: web
+# Number of replicas
+replicas: 2 # at least 2
+ports:
+- port: 80
+  # TCP or UDP
+  protocol: TCP
+# end of ports
+limits:
+  # In cores
+  cpu: 500m
+`,
+		},
+		{
+			name:   "YAML with document start",
+			format: FormatYAML,
+			opts:   []yaml.EncodeOption{ExplicitDocumentStart(), HeaderComment("line 1\nline 2", "")},
+			want: `# line 1
+# line 2
+#
+---
+# Name of the service, unique per cluster
+name: web
+# Number of replicas
+replicas: 2 # at least 2
+ports:
+- port: 80
+  # TCP or UDP
+  protocol: TCP
+# end of ports
+limits:
+  # In cores
+  cpu: 500m
+`,
+		},
+		{
+			name:   "JSON drops comments",
+			format: FormatJSON,
+			want:   `{"name": "web", "replicas": 2, "ports": [{"port": 80, "protocol": "TCP"}], "limits": {"cpu": "500m"}}` + "\n",
+		},
+		{
+			name:   "JSONC",
+			format: FormatJSON,
+			opts:   []yaml.EncodeOption{JSONC()},
+			want: `// Generated by tool X; do not edit
+{/* Name of the service, unique per cluster */ "name": "web", /* Number of replicas */ "replicas": 2 /* at least 2 */, "ports": [{"port": 80, /* TCP or UDP */ "protocol": "TCP"}] /* end of ports */, "limits": {/* In cores */ "cpu": "500m"}}
+`,
+		},
+		{
+			name:   "indented JSONC",
+			format: FormatJSONPretty,
+			opts:   []yaml.EncodeOption{JSONC()},
+			want: `// Generated by tool X; do not edit
+{
+  // Name of the service, unique per cluster
+  "name": "web",
+  // Number of replicas
+  "replicas": 2, // at least 2
+  "ports": [
+    {
+      "port": 80,
+      // TCP or UDP
+      "protocol": "TCP"
+    }
+  ],
+  // end of ports
+  "limits": {
+    // In cores
+    "cpu": "500m"
+  }
+}
+`,
+		},
+		{
+			name:   "JSON Lines ignores JSONC",
+			format: FormatJSONL,
+			opts:   []yaml.EncodeOption{JSONC()},
+			want:   `{"name":"web","replicas":2,"ports":[{"port":80,"protocol":"TCP"}],"limits":{"cpu":"500m"}}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append(append([]yaml.EncodeOption{}, commentTestOptions...), tt.opts...)
+			got, err := tt.format.Marshal(commentTestValue, opts...)
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, string(got)); diff != "" {
+				t.Errorf("Marshal() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCommentsRootAndKeyOrder(t *testing.T) {
+	v := yaml.MapSlice{{Key: "b", Value: 1}, {Key: "a", Value: []int{1, 2}}}
+	opts := []yaml.EncodeOption{
+		Comments(yaml.CommentMap{
+			".":     {yaml.HeadComment(" document")},
+			".a":    {yaml.HeadComment(" about a")},
+			".a[1]": {yaml.LineComment(" second")},
+		}),
+		OrderKeys(AlphabeticalKeys()),
+	}
+	got, err := Marshal(v, opts...)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want := "# document\n# about a\na:\n- 1\n- 2 # second\nb: 1\n"
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("Marshal() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+type commentTestItem struct {
+	B int `yaml:"b" yamlformat:"comment=bee"`
+	A int `yaml:"a" yamlformat:"comment=ay"`
+}
+
+func TestCommentsKeyOrderInSequences(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		opts  []yaml.EncodeOption
+		want  string
+	}{
+		{
+			name:  "comment map",
+			value: map[string]interface{}{"items": []map[string]int{{"b": 1, "a": 2}, {"b": 3, "a": 4}}},
+			opts: []yaml.EncodeOption{
+				Comments(yaml.CommentMap{
+					".items[0].a": {yaml.HeadComment(" first a")},
+					".items[1].a": {yaml.HeadComment(" second a")},
+					".items[1].b": {yaml.LineComment(" second b")},
+				}),
+				OrderKeys(PriorityKeys("b")),
+			},
+			want: "items:\n- b: 1\n  # first a\n  a: 2\n- b: 3 # second b\n  # second a\n  a: 4\n",
+		},
+		{
+			name:  "struct tags",
+			value: map[string][]commentTestItem{"items": {{B: 1, A: 2}, {B: 3, A: 4}}},
+			opts:  []yaml.EncodeOption{OrderKeys(AlphabeticalKeys().WithStructFields())},
+			want:  "items:\n- # ay\n  a: 2\n  # bee\n  b: 1\n- # ay\n  a: 4\n  # bee\n  b: 3\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Marshal(tt.value, tt.opts...)
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, string(got)); diff != "" {
+				t.Errorf("Marshal() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCommentsQuotedKeys(t *testing.T) {
+	v := yaml.MapSlice{
+		{Key: "a.b", Value: 1},
+		{Key: "ports", Value: map[string]commentTestPort{"web.http": {Port: 80, Protocol: "TCP"}}},
+		{Key: "x y", Value: []int{1}},
+		{Key: "it's.", Value: commentTestPort{Port: 443}},
+	}
+	opts := []yaml.EncodeOption{Comments(yaml.CommentMap{
+		`."a.b"`:     {yaml.LineComment(" dotted")},
+		`["x y"][0]`: {yaml.LineComment(" spaced")},
+		`."it's."`:   {yaml.LineComment(" ignored")},
+	})}
+	got, err := Marshal(v, opts...)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want := `a.b: 1 # dotted
+ports:
+  web.http:
+    port: 80
+    # TCP or UDP
+    protocol: TCP
+x y:
+- 1 # spaced
+it's.:
+  port: 443
+  protocol: ""
+`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("Marshal() mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err = MarshalJSON(v, append(opts, JSONC())...)
+	if err != nil {
+		t.Fatalf("MarshalJSON failed: %v", err)
+	}
+	want = `{"a.b": 1 /* dotted */, "ports": {"web.http": {"port": 80, /* TCP or UDP */ "protocol": "TCP"}}, "x y": [1 /* spaced */], "it's.": {"port": 443, "protocol": ""}}` + "\n"
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("MarshalJSON() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestHeaderCommentStreams(t *testing.T) {
+	opts := []yaml.EncodeOption{HeaderComment("generated"), Comments(yaml.CommentMap{".a": {yaml.LineComment(" c")}})}
+	values := []interface{}{map[string]int{"a": 1}, map[string]int{"a": 2}}
+	want := "# generated\na: 1 # c\n---\na: 2 # c\n"
+
+	got, err := MarshalAll(values, opts...)
+	if err != nil {
+		t.Fatalf("MarshalAll failed: %v", err)
+	}
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("MarshalAll() mismatch (-want +got):\n%s", diff)
+	}
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf, opts...)
+	for _, v := range values {
+		if err := enc.Encode(v); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("NewEncoder() mismatch (-want +got):\n%s", diff)
+	}
+
+	buf.Reset()
+	stream := NewStreamEncoder(&buf, FormatJSON, StreamArray, append(opts, JSONC())...)
+	for _, v := range values {
+		if err := stream.Encode(v); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if want := "// generated\n[{\"a\": 1 /* c */}, {\"a\": 2 /* c */}]\n"; buf.String() != want {
+		t.Errorf("NewStreamEncoder() wrote %q, want %q", buf.String(), want)
+	}
+}
+
+func TestFooterComment(t *testing.T) {
+	opts := []yaml.EncodeOption{HeaderComment("generated"), FooterComment("end of file")}
+	values := []interface{}{map[string]int{"a": 1}, map[string]int{"a": 2}}
+
+	got, err := Marshal(values[0], opts...)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if want := "# generated\na: 1\n# end of file\n"; string(got) != want {
+		t.Errorf("Marshal() = %q, want %q", got, want)
+	}
+
+	got, err = MarshalJSONIndent(values[0], "", "  ", append(opts, JSONC())...)
+	if err != nil {
+		t.Fatalf("MarshalJSONIndent failed: %v", err)
+	}
+	if want := "// generated\n{\n  \"a\": 1\n}\n// end of file\n"; string(got) != want {
+		t.Errorf("MarshalJSONIndent() = %q, want %q", got, want)
+	}
+
+	want := "# generated\na: 1\n---\na: 2\n# end of file\n"
+	got, err = MarshalAll(values, opts...)
+	if err != nil {
+		t.Fatalf("MarshalAll failed: %v", err)
+	}
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("MarshalAll() mismatch (-want +got):\n%s", diff)
+	}
+
+	var buf bytes.Buffer
+	enc := NewStreamEncoder(&buf, FormatYAML, StreamDocuments, opts...)
+	for _, v := range values {
+		if err := enc.Encode(v); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("NewStreamEncoder() mismatch (-want +got):\n%s", diff)
+	}
+
+	buf.Reset()
+	stream := NewStreamEncoder(&buf, FormatJSON, StreamArray, append(opts, JSONC())...)
+	for _, v := range values {
+		if err := stream.Encode(v); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if want := "// generated\n[{\"a\": 1}, {\"a\": 2}]\n// end of file\n"; buf.String() != want {
+		t.Errorf("NewStreamEncoder() wrote %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,270 @@
+package yamlformat
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/parser"
+)
+
+// convertSettings are the decode settings of Convert: mappings keep the source order
+// and numbers keep their literals where JSON allows it
+var convertSettings = decodeSettings{numbers: NumberJSONNumber, ordered: true}
+
+// Convert converts data from one format to another, e.g. YAML to JSON.
+// Unlike unmarshaling into interface{} and marshaling again, mappings keep the order of the source.
+// Anchors, aliases and merge keys are expanded, also when converting YAML to FormatYAML: the output has no anchors,
+// and each alias is replaced by the value of its anchor. Comments are kept when converting YAML to FormatYAML.
+// Numbers are written as in the source, even if they are beyond the range of float64 (1e400)
+// or DefaultBigNumbers is removed.
+// Each document (or JSON value, or JSON line) of data becomes a document of the output.
+// opts are passed to the target format, e.g. to set the indentation.
+func Convert(data []byte, from, to Format, opts ...yaml.EncodeOption) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := convert(&buf, bytes.NewReader(data), from, to, opts); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ConvertStream is like Convert but reads from r and writes to w.
+// JSON values, JSON Lines records and the documents of registered formats are read one at a time,
+// and each document is written as soon as it is converted. A YAML stream is read completely first.
+func ConvertStream(w io.Writer, r io.Reader, from, to Format, opts ...yaml.EncodeOption) error {
+	return convert(w, r, from, to, opts)
+}
+
+// Convert is like Convert but uses o instead of the package defaults for the target format
+func (o Options) Convert(data []byte, from, to Format, opts ...yaml.EncodeOption) ([]byte, error) {
+	return Convert(data, from, to, o.encodeOptions(opts)...)
+}
+
+// ConvertStream is like ConvertStream but uses o instead of the package defaults for the target format
+func (o Options) ConvertStream(w io.Writer, r io.Reader, from, to Format, opts ...yaml.EncodeOption) error {
+	return ConvertStream(w, r, from, to, o.encodeOptions(opts)...)
+}
+
+// convertedDocument is a document read by Convert
+type convertedDocument struct {
+	value    interface{}
+	comments yaml.CommentMap
+}
+
+func convert(w io.Writer, r io.Reader, from, to Format, opts []yaml.EncodeOption) error {
+	if err := from.Validate(); err != nil {
+		return err
+	}
+	if err := to.Validate(); err != nil {
+		return err
+	}
+	next, err := nextDocumentFunc(r, from, to == FormatYAML)
+	if err != nil {
+		return fmt.Errorf("convert from %s: %w", from, err)
+	}
+	opts = withNumberMarshalers(opts)
+
+	if to != FormatYAML {
+		enc := NewStreamEncoder(w, to, StreamDocuments, opts...)
+		for {
+			doc, err := next()
+			if errors.Is(err, io.EOF) {
+				return enc.Close()
+			}
+			if err != nil {
+				return fmt.Errorf("convert from %s: %w", from, err)
+			}
+			if err := enc.Encode(doc.value); err != nil {
+				return fmt.Errorf("convert to %s: %w", to, err)
+			}
+			if err := enc.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Marshal each document on its own, as comments are given per document.
+	// The footer comment is written once, after the last document.
+	footer := loadEncodeSettings(opts).documentSuffix()
+	opts = append(opts[:len(opts):len(opts)], withoutFooter())
+	for i := 0; ; i++ {
+		doc, err := next()
+		if errors.Is(err, io.EOF) {
+			if i > 0 {
+				_, err = io.WriteString(w, footer)
+				return err
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("convert from %s: %w", from, err)
+		}
+		if i == 1 {
+			// The header comment is written once, before the first document
+			opts = append(opts[:len(opts):len(opts)], withoutHeader())
+		}
+		docOpts := opts
+		if len(doc.comments) > 0 {
+			docOpts = append(append([]yaml.EncodeOption{}, opts...), Comments(doc.comments))
+		}
+		out, err := to.Marshal(doc.value, docOpts...)
+		if err != nil {
+			return fmt.Errorf("convert to %s: %w", to, err)
+		}
+		if i > 0 {
+			out = append([]byte("---\n"), out...)
+		}
+		if _, err := w.Write(out); err != nil {
+			return err
+		}
+	}
+}
+
+// withNumberMarshalers returns opts with DefaultBigNumbers enabled again if it was removed.
+// Converted numbers are json.Number values, which would be written as strings without its marshalers.
+func withNumberMarshalers(opts []yaml.EncodeOption) []yaml.EncodeOption {
+	without := loadEncodeSettings(opts).without
+	if !containsDefault(without, DefaultBigNumbers) {
+		return opts
+	}
+	var kept []Default
+	for _, d := range without {
+		if d != DefaultBigNumbers {
+			kept = append(kept, d)
+		}
+	}
+	return append(opts[:len(opts):len(opts)], settingOption(func(p withoutEncodeProbe) { p.s.without = kept }))
+}
+
+// nextDocumentFunc returns a function that reads the next document of r in format, or io.EOF after the last one.
+// JSON values, JSON Lines records and the documents of registered formats are read one at a time.
+// A YAML stream is read as a whole, with the comments of its documents if withComments is set.
+func nextDocumentFunc(r io.Reader, format Format, withComments bool) (func() (convertedDocument, error), error) {
+	var nextValue func() ([]byte, error)
+	switch format {
+	case FormatYAML:
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		docs, err := readYAMLDocuments(data, withComments)
+		if err != nil {
+			return nil, err
+		}
+		return func() (convertedDocument, error) {
+			if len(docs) == 0 {
+				return convertedDocument{}, io.EOF
+			}
+			doc := docs[0]
+			docs = docs[1:]
+			return doc, nil
+		}, nil
+	case FormatJSON, FormatJSONPretty:
+		nextValue = nextJSONValueFunc(r)
+	case FormatJSONL:
+		nextValue = nextJSONLineFunc(r)
+	default:
+		dec := format.NewDecoder(r, yaml.UseOrderedMap())
+		return func() (convertedDocument, error) {
+			var doc convertedDocument
+			err := dec.Decode(&doc.value)
+			return doc, err
+		}, nil
+	}
+	return func() (convertedDocument, error) {
+		value, err := nextValue()
+		if err != nil {
+			return convertedDocument{}, err
+		}
+		dec := json.NewDecoder(bytes.NewReader(value))
+		dec.UseNumber()
+		v, err := jsonTokenToValue(dec)
+		return convertedDocument{value: v}, err
+	}, nil
+}
+
+// jsonTokenToValue reads the next JSON value from dec, with objects as yaml.MapSlice in source order.
+// A repeated key keeps its first position and takes the last value, as in encoding/json.
+func jsonTokenToValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		m := yaml.MapSlice{}
+		index := map[string]int{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := jsonTokenToValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			if i, ok := index[key.(string)]; ok {
+				m[i].Value = value
+				continue
+			}
+			index[key.(string)] = len(m)
+			m = append(m, yaml.MapItem{Key: key.(string), Value: value})
+		}
+		_, err := dec.Token() // }
+		return m, err
+	case json.Delim('['):
+		values := []interface{}{}
+		for dec.More() {
+			value, err := jsonTokenToValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		_, err := dec.Token() // ]
+		return values, err
+	default:
+		return tok, nil
+	}
+}
+
+// readYAMLDocuments reads the documents of a YAML stream in source order
+func readYAMLDocuments(data []byte, withComments bool) ([]convertedDocument, error) {
+	mode := parser.Mode(0)
+	if withComments {
+		mode = parser.ParseComments
+	}
+	file, err := parser.ParseBytes(data, mode)
+	if err != nil {
+		return nil, err
+	}
+	if len(file.Docs) == 1 && file.Docs[0].Body == nil {
+		// Empty input, or only comments
+		return nil, nil
+	}
+	unmarshaler := interfaceUnmarshaler(convertSettings)
+	docs := make([]convertedDocument, 0, len(file.Docs))
+	for _, doc := range file.Docs {
+		if doc.Body == nil {
+			docs = append(docs, convertedDocument{})
+			continue
+		}
+		var d convertedDocument
+		// The unmarshaler receives the document with aliases resolved
+		if err := yaml.NodeToValue(doc.Body, &d.value, unmarshaler); err != nil {
+			return nil, err
+		}
+		if withComments {
+			d.comments = yaml.CommentMap{}
+			var discard interface{}
+			if err := yaml.NodeToValue(doc.Body, &discard, yaml.CommentToMap(d.comments)); err != nil {
+				return nil, err
+			}
+		}
+		docs = append(docs, d)
+	}
+	return docs, nil
+}
@@ -0,0 +1,321 @@
+package yamlformat
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const convertTestYAML = `# service
+name: web # the name
+replicas: 3
+base: &base
+  port: 8080
+  ratio: 1.10
+server:
+  <<: *base
+  port: 9090
+  id: 123456789012345678901234567890
+---
+zone: b
+area: a
+`
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		from  Format
+		to    Format
+		want  string
+	}{
+		{
+			name:  "YAML to YAML keeps comments",
+			input: convertTestYAML,
+			from:  FormatYAML,
+			to:    FormatYAML,
+			want: `# service
+name: web # the name
+replicas: 3
+base:
+  port: 8080
+  ratio: 1.10
+server:
+  port: 9090
+  ratio: 1.10
+  id: 123456789012345678901234567890
+---
+zone: b
+area: a
+`,
+		},
+		{
+			name:  "YAML to YAML expands aliases",
+			input: "a: &x [1, 2]\nb: *x\nc: &y\n  k: v\nd: *y\n",
+			from:  FormatYAML,
+			to:    FormatYAML,
+			want:  "a:\n- 1\n- 2\nb:\n- 1\n- 2\nc:\n  k: v\nd:\n  k: v\n",
+		},
+		{
+			name:  "YAML to JSON",
+			input: convertTestYAML,
+			from:  FormatYAML,
+			to:    FormatJSON,
+			want: `{"name": "web", "replicas": 3, "base": {"port": 8080, "ratio": 1.10}, "server": {"port": 9090, "ratio": 1.10, "id": 123456789012345678901234567890}}
+{"zone": "b", "area": "a"}
+`,
+		},
+		{
+			name:  "YAML to JSON Lines",
+			input: convertTestYAML,
+			from:  FormatYAML,
+			to:    FormatJSONL,
+			want: `{"name":"web","replicas":3,"base":{"port":8080,"ratio":1.10},"server":{"port":9090,"ratio":1.10,"id":123456789012345678901234567890}}
+{"zone":"b","area":"a"}
+`,
+		},
+		{
+			name:  "YAML to pretty JSON",
+			input: "b: [1, {d: null, c: true}]\na: x\n",
+			from:  FormatYAML,
+			to:    FormatJSONPretty,
+			want: `{
+  "b": [
+    1,
+    {
+      "d": null,
+      "c": true
+    }
+  ],
+  "a": "x"
+}
+`,
+		},
+		{
+			name:  "JSON to YAML",
+			input: `{"z": 1, "a": [1.50, {"q": null}], "s": "é\/"} {"b": {}}`,
+			from:  FormatJSON,
+			to:    FormatYAML,
+			want:  "z: 1\na:\n- 1.50\n- q: null\ns: é/\n---\nb: {}\n",
+		},
+		{
+			name:  "JSON duplicate keys",
+			input: `{"a": 1, "b": 2, "a": 3}`,
+			from:  FormatJSON,
+			to:    FormatYAML,
+			want:  "a: 3\nb: 2\n",
+		},
+		{
+			name:  "JSON Lines to pretty JSON",
+			input: "{\"b\": 1, \"a\": 2}\n{\"a\": 3}\n",
+			from:  FormatJSONL,
+			to:    FormatJSONPretty,
+			want:  "{\n  \"b\": 1,\n  \"a\": 2\n}\n{\n  \"a\": 3\n}\n",
+		},
+		{
+			name:  "empty YAML",
+			input: "",
+			from:  FormatYAML,
+			to:    FormatJSON,
+			want:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Convert([]byte(tt.input), tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("Convert failed: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, string(got)); diff != "" {
+				t.Errorf("Convert() mismatch (-want +got):\n%s", diff)
+			}
+
+			var buf bytes.Buffer
+			if err := ConvertStream(&buf, strings.NewReader(tt.input), tt.from, tt.to); err != nil {
+				t.Fatalf("ConvertStream failed: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, buf.String()); diff != "" {
+				t.Errorf("ConvertStream() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConvertNumberLikeStrings(t *testing.T) {
+	// goccy/go-yaml reads exponents without a fraction as strings, other YAML parsers as numbers
+	const input = `{"s": "2e5", "n": 2e5, "l": ["1E-3", 1E-3]}`
+	yamlOut, err := Convert([]byte(input), FormatJSON, FormatYAML)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if want := "s: \"2e5\"\n\"n\": 2.0e5\nl:\n- \"1E-3\"\n- 1.0E-3\n"; string(yamlOut) != want {
+		t.Errorf("Convert() to YAML = %q, want %q", yamlOut, want)
+	}
+
+	jsonOut, err := Convert(yamlOut, FormatYAML, FormatJSON)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if want := `{"s": "2e5", "n": 2.0e5, "l": ["1E-3", 1.0E-3]}` + "\n"; string(jsonOut) != want {
+		t.Errorf("Convert() back to JSON = %q, want %q", jsonOut, want)
+	}
+
+	var got interface{}
+	if err := Unmarshal(yamlOut, &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	want := map[string]interface{}{"s": "2e5", "n": 200000.0, "l": []interface{}{"1E-3", 0.001}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestConvertOutOfRangeNumbers(t *testing.T) {
+	// Numbers beyond the range of float64 are kept as numbers, also without the json.Number marshalers
+	const input = `{"a": 1e400, "b": [-1.5E+400, 1e-400]}`
+	for _, opts := range []Options{DefaultOptions(), DefaultOptions().Without(DefaultBigNumbers)} {
+		yamlOut, err := opts.Convert([]byte(input), FormatJSON, FormatYAML)
+		if err != nil {
+			t.Fatalf("Convert failed: %v", err)
+		}
+		if want := "a: 1.0e400\nb:\n- -1.5E+400\n- 1.0e-400\n"; string(yamlOut) != want {
+			t.Errorf("Convert() to YAML = %q, want %q", yamlOut, want)
+		}
+
+		jsonOut, err := opts.Convert(yamlOut, FormatYAML, FormatJSON)
+		if err != nil {
+			t.Fatalf("Convert failed: %v", err)
+		}
+		if want := `{"a": 1.0e400, "b": [-1.5E+400, 1.0e-400]}` + "\n"; string(jsonOut) != want {
+			t.Errorf("Convert() back to JSON = %q, want %q", jsonOut, want)
+		}
+	}
+
+	var got map[string]interface{}
+	if err := Unmarshal([]byte("a: 1.0e400\n"), &got, DecodeNumbers(NumberJSONNumber)); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if diff := cmp.Diff(map[string]interface{}{"a": json.Number("1.0e400")}, got); diff != "" {
+		t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestConvertStreamReadsValuesOneAtATime(t *testing.T) {
+	tests := []struct {
+		from, to Format
+		record   string
+		want     string
+	}{
+		{from: FormatJSON, to: FormatYAML, record: `{"id": 1}`, want: "id: 1\n"},
+		{from: FormatJSONL, to: FormatJSON, record: "{\"id\": 1}\n", want: "{\"id\": 1}\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"/"+string(tt.to), func(t *testing.T) {
+			inR, inW := io.Pipe()
+			outR, outW := io.Pipe()
+			done := make(chan error, 1)
+			go func() {
+				err := ConvertStream(outW, inR, tt.from, tt.to)
+				outW.CloseWithError(err)
+				done <- err
+			}()
+
+			// The first record is converted while the input is still open
+			go io.WriteString(inW, tt.record)
+			got := make([]byte, len(tt.want))
+			read := make(chan error, 1)
+			go func() {
+				_, err := io.ReadFull(outR, got)
+				read <- err
+			}()
+			select {
+			case err := <-read:
+				if err != nil {
+					t.Fatalf("reading the output failed: %v", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("ConvertStream did not write the first record before the input was closed")
+			}
+			if string(got) != tt.want {
+				t.Errorf("ConvertStream() wrote %q, want %q", got, tt.want)
+			}
+
+			inW.Close()
+			if _, err := io.ReadAll(outR); err != nil {
+				t.Errorf("reading the rest of the output failed: %v", err)
+			}
+			if err := <-done; err != nil {
+				t.Errorf("ConvertStream failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestConvertRegisteredFormat(t *testing.T) {
+	withTestRegistry(t)
+	if err := RegisterFormat(flowYAMLSpec); err != nil {
+		t.Fatalf("RegisterFormat failed: %v", err)
+	}
+
+	got, err := Convert([]byte("b: 1\na: [x, z]\n"), FormatYAML, "yaml-flow")
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if want := "{b: 1, a: [x, z]}\n"; string(got) != want {
+		t.Errorf("Convert() = %q, want %q", got, want)
+	}
+
+	got, err = Convert([]byte("{b: 1, a: 2}"), "yaml-flow", FormatJSON)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if want := `{"b": 1, "a": 2}` + "\n"; string(got) != want {
+		t.Errorf("Convert() = %q, want %q", got, want)
+	}
+}
+
+func TestOptionsConvert(t *testing.T) {
+	opts, err := Profile(ProfileCanonical)
+	if err != nil {
+		t.Fatalf("Profile failed: %v", err)
+	}
+	got, err := opts.Convert([]byte("b: 1\na: 2\n"), FormatYAML, FormatJSON)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if want := `{"a":2,"b":1}` + "\n"; string(got) != want {
+		t.Errorf("Convert() = %q, want %q", got, want)
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		from  Format
+		to    Format
+		want  string
+	}{
+		{name: "unknown source", from: "xml", to: FormatJSON, want: "invalid format: xml"},
+		{name: "unknown target", from: FormatYAML, to: "toml", want: "invalid format: toml"},
+		{name: "invalid JSON", input: `{"a": }`, from: FormatJSON, to: FormatYAML, want: "convert from json: invalid JSON"},
+		{name: "invalid JSON Lines", input: "{\"a\": 1}\n{", from: FormatJSONL, to: FormatYAML, want: "convert from jsonl:"},
+		{name: "invalid YAML", input: "a: [1", from: FormatYAML, to: FormatJSON, want: "convert from yaml:"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Convert([]byte(tt.input), tt.from, tt.to)
+			if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("Convert() error = %v, want prefix %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package yamlformat
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"path/filepath"
+	"strings"
+
+	"github.com/goccy/go-yaml/parser"
+)
+
+// FormatFromPath returns the format registered for the extension of path, case-insensitively
+// (e.g. ".yaml" and ".yml" for FormatYAML, ".jsonl" for FormatJSONL)
+func FormatFromPath(path string) (Format, bool) {
+	ext := filepath.Ext(path)
+	if ext == "" {
+		return "", false
+	}
+	return registry.byExtension(ext)
+}
+
+// FormatFromMediaType returns the format registered for a MIME type such as "application/yaml".
+// Parameters (e.g. "; charset=utf-8") are ignored, and unregistered types with
+// a "+json" or "+yaml" structured syntax suffix map to FormatJSON or FormatYAML.
+func FormatFromMediaType(mediaType string) (Format, bool) {
+	mt, _, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		return "", false
+	}
+	if format, ok := registry.byMediaType(mt); ok {
+		return format, true
+	}
+	switch {
+	case strings.HasSuffix(mt, "+json"):
+		return FormatJSON, true
+	case strings.HasSuffix(mt, "+yaml"):
+		return FormatYAML, true
+	}
+	return "", false
+}
+
+// Confidence is how certain DetectFormat is about the detected format
+type Confidence int
+
+const (
+	// ConfidenceNone means there was nothing to inspect; FormatYAML is returned
+	ConfidenceNone Confidence = iota
+	// ConfidenceLow means the input only looks like the format, e.g. it may be cut off
+	ConfidenceLow
+	// ConfidenceMedium means the input is valid in the format, but may be valid in another format too
+	ConfidenceMedium
+	// ConfidenceHigh means the input is valid in the format and unlikely to be anything else
+	ConfidenceHigh
+)
+
+func (c Confidence) String() string {
+	switch c {
+	case ConfidenceNone:
+		return "none"
+	case ConfidenceLow:
+		return "low"
+	case ConfidenceMedium:
+		return "medium"
+	case ConfidenceHigh:
+		return "high"
+	default:
+		return "unknown"
+	}
+}
+
+// utf8BOM is the UTF-8 byte order mark, which is skipped by DetectFormat
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// DetectFormat guesses the format of data by inspecting its content.
+// data may be only the leading bytes of the input, so a cut-off last value lowers the confidence
+// rather than failing. It recognizes:
+//   - a single JSON object or array as FormatJSON
+//   - one JSON value per line as FormatJSONL
+//   - a YAML document marker ("---" or a "%YAML" directive) or any other YAML as FormatYAML
+func DetectFormat(data []byte) (Format, Confidence) {
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, utf8BOM))
+	if len(data) == 0 {
+		return FormatYAML, ConfidenceNone
+	}
+
+	switch {
+	case data[0] == '{' || data[0] == '[':
+		if json.Valid(data) {
+			return FormatJSON, ConfidenceHigh
+		}
+		if lines := bytes.Split(data, []byte("\n")); len(lines) > 1 {
+			if _, err := splitJSONLines(data); err == nil {
+				return FormatJSONL, ConfidenceHigh
+			}
+			// The last line may be cut off
+			if json.Valid(bytes.TrimSpace(lines[0])) {
+				return FormatJSONL, ConfidenceMedium
+			}
+		}
+		if isYAML(data) {
+			// YAML in flow style, such as {a: 1}
+			return FormatYAML, ConfidenceMedium
+		}
+		// JSON that is cut off
+		return FormatJSON, ConfidenceLow
+	case bytes.HasPrefix(data, []byte("---")) || bytes.HasPrefix(data, []byte("%YAML")):
+		return FormatYAML, ConfidenceHigh
+	case json.Valid(data):
+		// A JSON scalar, which is YAML too
+		return FormatJSON, ConfidenceLow
+	case isYAML(data):
+		return FormatYAML, ConfidenceMedium
+	default:
+		return FormatYAML, ConfidenceLow
+	}
+}
+
+// isYAML reports whether data parses as YAML
+func isYAML(data []byte) bool {
+	_, err := parser.ParseBytes(data, 0)
+	return err == nil
+}
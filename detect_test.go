@@ -0,0 +1,93 @@
+package yamlformat
+
+import "testing"
+
+func TestFormatFromPath(t *testing.T) {
+	tests := []struct {
+		path   string
+		want   Format
+		wantOK bool
+	}{
+		{path: "config.yaml", want: FormatYAML, wantOK: true},
+		{path: "dir/config.YML", want: FormatYAML, wantOK: true},
+		{path: "data.json", want: FormatJSON, wantOK: true},
+		{path: "/var/log/events.jsonl", want: FormatJSONL, wantOK: true},
+		{path: "events.ndjson", want: FormatJSONL, wantOK: true},
+		{path: "notes.txt"},
+		{path: "Makefile"},
+		{path: "archive.json.gz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, ok := FormatFromPath(tt.path)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("FormatFromPath(%q) = %q, %v, want %q, %v", tt.path, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestFormatFromMediaType(t *testing.T) {
+	tests := []struct {
+		mediaType string
+		want      Format
+		wantOK    bool
+	}{
+		{mediaType: "application/yaml", want: FormatYAML, wantOK: true},
+		{mediaType: "application/x-yaml", want: FormatYAML, wantOK: true},
+		{mediaType: "text/yaml; charset=utf-8", want: FormatYAML, wantOK: true},
+		{mediaType: "application/json", want: FormatJSON, wantOK: true},
+		{mediaType: "Application/JSON; charset=UTF-8", want: FormatJSON, wantOK: true},
+		{mediaType: "application/problem+json", want: FormatJSON, wantOK: true},
+		{mediaType: "application/vnd.api+json", want: FormatJSON, wantOK: true},
+		{mediaType: "application/vnd.oai.openapi+yaml", want: FormatYAML, wantOK: true},
+		{mediaType: "application/x-ndjson", want: FormatJSONL, wantOK: true},
+		{mediaType: "application/jsonl", want: FormatJSONL, wantOK: true},
+		{mediaType: "text/plain"},
+		{mediaType: "not a media type"},
+		{mediaType: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mediaType, func(t *testing.T) {
+			got, ok := FormatFromMediaType(tt.mediaType)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("FormatFromMediaType(%q) = %q, %v, want %q, %v", tt.mediaType, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		want       Format
+		confidence Confidence
+	}{
+		{name: "empty", input: " \n", want: FormatYAML, confidence: ConfidenceNone},
+		{name: "JSON object", input: `{"a": 1, "b": [1, 2]}`, want: FormatJSON, confidence: ConfidenceHigh},
+		{name: "JSON array with BOM", input: "\xef\xbb\xbf[1, 2]\n", want: FormatJSON, confidence: ConfidenceHigh},
+		{name: "indented JSON", input: "{\n  \"a\": 1\n}\n", want: FormatJSON, confidence: ConfidenceHigh},
+		{name: "cut-off JSON", input: "{\n  \"a\": 1,\n  \"b", want: FormatJSON, confidence: ConfidenceLow},
+		{name: "JSON Lines", input: "{\"a\": 1}\n{\"a\": 2}\n", want: FormatJSONL, confidence: ConfidenceHigh},
+		{name: "cut-off JSON Lines", input: "{\"a\": 1}\n{\"a\": 2}\n{\"a\"", want: FormatJSONL, confidence: ConfidenceMedium},
+		{name: "YAML flow mapping", input: "{a: 1}", want: FormatYAML, confidence: ConfidenceMedium},
+		{name: "YAML document marker", input: "---\na: 1\n", want: FormatYAML, confidence: ConfidenceHigh},
+		{name: "YAML directive", input: "%YAML 1.2\n---\na: 1\n", want: FormatYAML, confidence: ConfidenceHigh},
+		{name: "YAML mapping", input: "# config\na: 1\nb: [1, 2]\n", want: FormatYAML, confidence: ConfidenceMedium},
+		{name: "YAML sequence", input: "- a\n- b\n", want: FormatYAML, confidence: ConfidenceMedium},
+		{name: "invalid YAML", input: "a: 1\n b: 2\n  - c", want: FormatYAML, confidence: ConfidenceLow},
+		{name: "JSON scalar", input: `"text"`, want: FormatJSON, confidence: ConfidenceLow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, confidence := DetectFormat([]byte(tt.input))
+			if got != tt.want || confidence != tt.confidence {
+				t.Errorf("DetectFormat(%q) = %q, %v, want %q, %v", tt.input, got, confidence, tt.want, tt.confidence)
+			}
+		})
+	}
+}
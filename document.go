@@ -0,0 +1,1128 @@
+package yamlformat
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/lexer"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/goccy/go-yaml/token"
+)
+
+// ErrPathNotFound is returned (wrapped) by Document methods when a path does not exist
+var ErrPathNotFound = errors.New("path not found")
+
+// Document is a document that can be edited in place, e.g. to bump a version in a hand-maintained file.
+// Edits of a YAML document only rewrite the text of the values they change, so comments, key order,
+// quoting style, anchors and blank lines are kept everywhere else.
+// Values written by Set and Append are marshaled with the options given to ParseDocument.
+//
+// Paths are like ".spec.containers[0].image": mapping keys follow a ".", and sequence indexes are in brackets.
+// A key containing "." or "[" is quoted as a JSON string, like ."app.kubernetes.io/name" or ["a.b"].
+// A leading "$" is allowed, and "" or "." is the whole document.
+type Document struct {
+	format Format
+	opts   []yaml.EncodeOption
+	src    []byte
+	root   interface{} // the value of a JSON document, with objects as yaml.MapSlice
+}
+
+// ParseDocument parses data as a single document in format for editing.
+// Documents of every format are edited as text. FormatJSON and FormatJSONPretty documents have no comments;
+// their changed values are written in the indentation and spacing of the source.
+func ParseDocument(data []byte, format Format, opts ...yaml.EncodeOption) (*Document, error) {
+	if err := format.Validate(); err != nil {
+		return nil, err
+	}
+	d := &Document{format: format, opts: opts}
+	switch format {
+	case FormatYAML:
+		file, err := parser.ParseBytes(data, 0)
+		if err != nil {
+			return nil, fmt.Errorf("parse document: %w", err)
+		}
+		if len(file.Docs) > 1 {
+			return nil, fmt.Errorf("parse document: found %d documents, want one", len(file.Docs))
+		}
+		d.src = append([]byte(nil), data...)
+	case FormatJSON, FormatJSONPretty:
+		values, err := splitJSONValues(data)
+		if err != nil {
+			return nil, fmt.Errorf("parse document: %w", err)
+		}
+		if len(values) != 1 {
+			return nil, fmt.Errorf("parse document: found %d JSON values, want one", len(values))
+		}
+		if d.root, err = parseJSONTree(values[0]); err != nil {
+			return nil, fmt.Errorf("parse document: %w", err)
+		}
+		d.src = append([]byte(nil), data...)
+	default:
+		return nil, fmt.Errorf("parse document: format %s is not supported", format)
+	}
+	return d, nil
+}
+
+// ParseDocument is like ParseDocument but marshals the values written to the document with o
+func (o Options) ParseDocument(data []byte, format Format, opts ...yaml.EncodeOption) (*Document, error) {
+	return ParseDocument(data, format, o.encodeOptions(opts)...)
+}
+
+// Format returns the format of the document
+func (d *Document) Format() Format {
+	return d.format
+}
+
+// Bytes returns the current text of the document
+func (d *Document) Bytes() []byte {
+	return append([]byte(nil), d.src...)
+}
+
+// String returns the current text of the document
+func (d *Document) String() string {
+	return string(d.src)
+}
+
+// Has reports whether path exists in the document
+func (d *Document) Has(path string) bool {
+	steps, err := parseDocumentPath(path)
+	if err != nil {
+		return false
+	}
+	root, err := d.value()
+	if err != nil {
+		return false
+	}
+	_, ok := lookupTree(root, steps)
+	return ok
+}
+
+// Get decodes the value at path into v, like the format's Unmarshal.
+// Aliases and merge keys are resolved.
+func (d *Document) Get(path string, v interface{}, opts ...yaml.DecodeOption) error {
+	steps, err := parseDocumentPath(path)
+	if err != nil {
+		return err
+	}
+	// JSON documents are parsed as YAML too, like UnmarshalJSON does
+	s, err := d.parseYAMLSource()
+	if err != nil {
+		return err
+	}
+	node, ok := s.lookupMerged(resolveAliases(s.body, map[string]ast.Node{}), steps)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrPathNotFound, formatPath(steps))
+	}
+	if node == nil {
+		return nil
+	}
+	return yaml.NodeToValue(node, v, allUnmarshalOptions(opts)...)
+}
+
+// Set sets the value at path. A missing key is added at the end of its mapping,
+// but the mapping itself must exist; a sequence index must be in range.
+// A scalar replaced by a scalar keeps its quoting style, anchor, tag and line comment;
+// a value written on the following lines instead keeps the line comment after its key or "-".
+func (d *Document) Set(path string, value interface{}) error {
+	steps, err := parseDocumentPath(path)
+	if err != nil {
+		return err
+	}
+	if d.format != FormatYAML {
+		return d.updateJSON(steps, func(interface{}, bool) (interface{}, bool, error) { return value, false, nil })
+	}
+	return d.setYAML(steps, value)
+}
+
+// Delete removes the mapping entry or sequence item at path
+func (d *Document) Delete(path string) error {
+	steps, err := parseDocumentPath(path)
+	if err != nil {
+		return err
+	}
+	if len(steps) == 0 {
+		return errors.New("cannot delete the document root")
+	}
+	if d.format != FormatYAML {
+		return d.updateJSON(steps, func(_ interface{}, ok bool) (interface{}, bool, error) {
+			if !ok {
+				return nil, false, fmt.Errorf("%w: %s", ErrPathNotFound, formatPath(steps))
+			}
+			return nil, true, nil
+		})
+	}
+	return d.deleteYAML(steps)
+}
+
+// Append adds value to the end of the sequence at path.
+// A missing or null value at path is set to a sequence of value.
+func (d *Document) Append(path string, value interface{}) error {
+	steps, err := parseDocumentPath(path)
+	if err != nil {
+		return err
+	}
+	if d.format != FormatYAML {
+		return d.updateJSON(steps, func(cur interface{}, ok bool) (interface{}, bool, error) {
+			switch cur := cur.(type) {
+			case nil:
+				return []interface{}{value}, false, nil
+			case []interface{}:
+				return append(cur, value), false, nil
+			default:
+				return nil, false, fmt.Errorf("path %s: value is not a sequence", formatPath(steps))
+			}
+		})
+	}
+	return d.appendYAML(steps, value)
+}
+
+// value returns the value of the whole document, with mappings as yaml.MapSlice
+func (d *Document) value() (interface{}, error) {
+	if d.format != FormatYAML {
+		return d.root, nil
+	}
+	file, err := parser.ParseBytes(d.src, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(file.Docs) == 0 || file.Docs[0].Body == nil {
+		return nil, nil
+	}
+	var v interface{}
+	if err := yaml.NodeToValue(file.Docs[0].Body, &v, interfaceUnmarshaler(convertSettings)); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// parseJSONTree reads a JSON value with objects as yaml.MapSlice and numbers as json.Number
+func parseJSONTree(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return jsonTokenToValue(dec)
+}
+
+// pathStep is a step of a Document path: a mapping key, or a sequence index if isIndex is set
+type pathStep struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// parseDocumentPath splits a path like ".spec.items[0].name" into its steps.
+// Keys may be quoted as JSON strings, like ."a.b" or ["a.b"].
+func parseDocumentPath(path string) ([]pathStep, error) {
+	p := strings.TrimPrefix(path, "$")
+	if p == "" || p == "." {
+		return nil, nil
+	}
+	var steps []pathStep
+	for p != "" {
+		switch {
+		case strings.HasPrefix(p, `."`):
+			key, rest, err := quotedPathKey(p[1:])
+			if err != nil {
+				return nil, fmt.Errorf("invalid path %q: %w", path, err)
+			}
+			steps = append(steps, pathStep{key: key})
+			p = rest
+		case strings.HasPrefix(p, `["`), strings.HasPrefix(p, `.["`):
+			key, rest, err := quotedPathKey(strings.TrimPrefix(p, ".")[1:])
+			if err != nil {
+				return nil, fmt.Errorf("invalid path %q: %w", path, err)
+			}
+			if !strings.HasPrefix(rest, "]") {
+				return nil, fmt.Errorf("invalid path %q: missing \"]\"", path)
+			}
+			steps = append(steps, pathStep{key: key})
+			p = rest[1:]
+		case p[0] == '.':
+			end := strings.IndexAny(p[1:], ".[") + 1
+			if end == 0 {
+				end = len(p)
+			}
+			if end == 1 {
+				return nil, fmt.Errorf("invalid path %q: empty key", path)
+			}
+			steps = append(steps, pathStep{key: p[1:end]})
+			p = p[end:]
+		case p[0] == '[':
+			end := strings.IndexByte(p, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid path %q: missing \"]\"", path)
+			}
+			index, err := strconv.Atoi(p[1:end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid path %q: invalid index %q", path, p[1:end])
+			}
+			steps = append(steps, pathStep{index: index, isIndex: true})
+			p = p[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid path %q: must start with \".\" or \"[\"", path)
+		}
+	}
+	return steps, nil
+}
+
+// quotedPathKey reads the JSON string at the start of p and returns its value and the rest of p
+func quotedPathKey(p string) (key, rest string, err error) {
+	for i := 1; i < len(p); i++ {
+		switch p[i] {
+		case '\\':
+			i++
+		case '"':
+			if err := json.Unmarshal([]byte(p[:i+1]), &key); err != nil {
+				return "", "", fmt.Errorf("invalid key %s", p[:i+1])
+			}
+			return key, p[i+1:], nil
+		}
+	}
+	return "", "", fmt.Errorf("unterminated key %s", p)
+}
+
+// formatPath formats steps as a path, quoting the keys that need it
+func formatPath(steps []pathStep) string {
+	if len(steps) == 0 {
+		return "."
+	}
+	var b strings.Builder
+	for _, step := range steps {
+		switch {
+		case step.isIndex:
+			fmt.Fprintf(&b, "[%d]", step.index)
+		case step.key == "" || strings.ContainsAny(step.key, `.["`):
+			key, _ := json.Marshal(step.key)
+			b.WriteString("." + string(key))
+		default:
+			b.WriteString("." + step.key)
+		}
+	}
+	return b.String()
+}
+
+// lookupTree returns the value at steps of a value with mappings as yaml.MapSlice.
+// The last of duplicate keys wins, as when decoding.
+func lookupTree(v interface{}, steps []pathStep) (interface{}, bool) {
+	for _, step := range steps {
+		switch c := v.(type) {
+		case yaml.MapSlice:
+			i := mapSliceIndex(c, step)
+			if i < 0 {
+				return nil, false
+			}
+			v = c[i].Value
+		case []interface{}:
+			if !step.isIndex || step.index >= len(c) {
+				return nil, false
+			}
+			v = c[step.index]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// mapSliceIndex returns the index of the last item of m with the key of step, or -1
+func mapSliceIndex(m yaml.MapSlice, step pathStep) int {
+	if step.isIndex {
+		return -1
+	}
+	for i := len(m) - 1; i >= 0; i-- {
+		if fmt.Sprint(m[i].Key) == step.key {
+			return i
+		}
+	}
+	return -1
+}
+
+// treeUpdate returns the new value for the current value at a path, which is missing if ok is false.
+// If remove is set, the value is removed from its mapping or sequence instead.
+type treeUpdate func(cur interface{}, ok bool) (v interface{}, remove bool, err error)
+
+// updateTree applies update to the value at steps[i:] of v and returns the new v
+func updateTree(v interface{}, steps []pathStep, i int, update treeUpdate) (interface{}, error) {
+	if i == len(steps) {
+		v, remove, err := update(v, true)
+		if err == nil && remove {
+			err = errors.New("cannot delete the document root")
+		}
+		return v, err
+	}
+	step, last := steps[i], i == len(steps)-1
+	switch c := v.(type) {
+	case yaml.MapSlice:
+		j := mapSliceIndex(c, step)
+		switch {
+		case j >= 0 && last:
+			value, remove, err := update(c[j].Value, true)
+			if err != nil {
+				return nil, err
+			}
+			if remove {
+				return append(c[:j:j], c[j+1:]...), nil
+			}
+			c[j].Value = value
+			return c, nil
+		case j >= 0:
+			value, err := updateTree(c[j].Value, steps, i+1, update)
+			if err != nil {
+				return nil, err
+			}
+			c[j].Value = value
+			return c, nil
+		case last && !step.isIndex:
+			value, _, err := update(nil, false)
+			if err != nil {
+				return nil, err
+			}
+			return append(c, yaml.MapItem{Key: step.key, Value: value}), nil
+		}
+	case []interface{}:
+		if step.isIndex && step.index < len(c) {
+			if !last {
+				value, err := updateTree(c[step.index], steps, i+1, update)
+				if err != nil {
+					return nil, err
+				}
+				c[step.index] = value
+				return c, nil
+			}
+			value, remove, err := update(c[step.index], true)
+			if err != nil {
+				return nil, err
+			}
+			if remove {
+				return append(c[:step.index:step.index], c[step.index+1:]...), nil
+			}
+			c[step.index] = value
+			return c, nil
+		}
+	case nil:
+		if last && !step.isIndex {
+			value, _, err := update(nil, false)
+			if err != nil {
+				return nil, err
+			}
+			return yaml.MapSlice{{Key: step.key, Value: value}}, nil
+		}
+	default:
+		return nil, fmt.Errorf("path %s: value at %s is not a mapping or sequence", formatPath(steps), formatPath(steps[:i]))
+	}
+	return nil, fmt.Errorf("%w: %s", ErrPathNotFound, formatPath(steps[:i+1]))
+}
+
+// yamlSource is the source of a YAML document being edited, with the offsets of its lines
+type yamlSource struct {
+	data  []byte
+	lines []int
+	body  ast.Node
+	// tags are the columns of the tag tokens of each line, as given by goccy/go-yaml
+	tags map[int][]int
+}
+
+// parseYAMLSource parses the YAML document of d
+func (d *Document) parseYAMLSource() (*yamlSource, error) {
+	file, err := parser.ParseBytes(d.src, 0)
+	if err != nil {
+		return nil, err
+	}
+	return newYAMLSource(d.src, file), nil
+}
+
+// newYAMLSource returns the source data of file, whose first document is the body
+func newYAMLSource(data []byte, file *ast.File) *yamlSource {
+	s := &yamlSource{data: data, lines: []int{0}, tags: map[int][]int{}}
+	for i, c := range data {
+		if c == '\n' {
+			s.lines = append(s.lines, i+1)
+		}
+	}
+	for _, tk := range lexer.Tokenize(string(data)) {
+		if tk.Type == token.TagType {
+			s.tags[tk.Position.Line] = append(s.tags[tk.Position.Line], tk.Position.Column)
+		}
+	}
+	if len(file.Docs) > 0 {
+		s.body = file.Docs[0].Body
+	}
+	return s
+}
+
+// offset returns the byte offset of a token, whose column counts runes.
+// goccy/go-yaml gives the tokens after a tag a column one less than their own for each tag before them
+// on their line, which is corrected.
+func (s *yamlSource) offset(tk *token.Token) int {
+	column := tk.Position.Column
+	for _, tag := range s.tags[tk.Position.Line] {
+		if tag < tk.Position.Column {
+			column++
+		}
+	}
+	off := s.lines[tk.Position.Line-1]
+	for col := 1; col < column && off < len(s.data); col++ {
+		_, size := utf8.DecodeRune(s.data[off:])
+		off += size
+	}
+	return off
+}
+
+// line returns the index of the line holding offset
+func (s *yamlSource) line(offset int) int {
+	i := len(s.lines) - 1
+	for i > 0 && s.lines[i] > offset {
+		i--
+	}
+	return i
+}
+
+// lineEnd returns the offset of the end of a line, before its newline
+func (s *yamlSource) lineEnd(line int) int {
+	if line+1 < len(s.lines) {
+		return s.lines[line+1] - 1
+	}
+	return len(s.data)
+}
+
+// column returns the byte column of offset in its line
+func (s *yamlSource) column(offset int) int {
+	return offset - s.lines[s.line(offset)]
+}
+
+// blockEnd returns the end of the last line of the block starting at offset, before its newline.
+// The block continues while lines are indented more than indent; trailing blank and comment lines
+// are not part of it.
+func (s *yamlSource) blockEnd(offset, indent int) int {
+	first := s.line(offset)
+	last := first
+	for l := first + 1; l < len(s.lines); l++ {
+		text := string(s.data[s.lines[l]:s.lineEnd(l)])
+		if isBlankOrCommentLine(text) {
+			continue
+		}
+		if len(text)-len(strings.TrimLeft(text, " ")) <= indent {
+			break
+		}
+		last = l
+	}
+	return s.lineEnd(last)
+}
+
+// elementEnd returns the end of the last line of element e of a block collection, before its newline.
+// Unlike blockEnd, it covers the items of a block sequence value that start in the column of their key.
+func (s *yamlSource) elementEnd(e yamlElement) int {
+	indent := s.column(e.start)
+	end := s.blockEnd(e.start, indent)
+	if items, ok := s.collection(e.value); ok && !items.mapping && !items.flow && len(items.elements) > 0 {
+		end = max(end, s.blockEnd(items.elements[len(items.elements)-1].start, indent))
+	}
+	return end
+}
+
+// headCommentsStart returns the offset of the first of the comment lines right above offset that start
+// in its column, or offset if there are none. A comment after a "-" counts as one of those lines.
+func (s *yamlSource) headCommentsStart(offset int) int {
+	column := s.column(offset)
+	start := offset
+	for l := s.line(offset) - 1; l >= 0; l-- {
+		text := string(s.data[s.lines[l]:s.lineEnd(l)])
+		if len(text) <= column || text[column] != '#' || strings.Trim(text[:column], " -") != "" {
+			break
+		}
+		start = s.lines[l] + column
+	}
+	return start
+}
+
+// trimEnd returns end moved back over the blank lines and the comment lines indented at most indent
+// before it, but not before start. end is the end of a line.
+func (s *yamlSource) trimEnd(start, end, indent int) int {
+	for l := s.line(end); end > start; l-- {
+		text := string(s.data[s.lines[l]:end])
+		if !isBlankOrCommentLine(text) || len(text)-len(strings.TrimLeft(text, " ")) > indent && strings.TrimSpace(text) != "" {
+			break
+		}
+		end = max(s.lines[l]-1, start)
+	}
+	return end
+}
+
+// valueRange returns the offsets of an inline value: a scalar or alias on a single line, or a flow collection.
+// The range excludes the anchor and tag of the value. ok is false for anything else.
+func (s *yamlSource) valueRange(n ast.Node, inFlow bool) (start, end int, ok bool) {
+	n = unwrapNode(n)
+	switch v := n.(type) {
+	case *ast.MappingNode:
+		if !v.IsFlowStyle {
+			return 0, 0, false
+		}
+		return s.offset(v.Start), s.offset(v.End) + 1, true
+	case *ast.SequenceNode:
+		if !v.IsFlowStyle {
+			return 0, 0, false
+		}
+		return s.offset(v.Start), s.offset(v.End) + 1, true
+	case *ast.MappingValueNode, *ast.LiteralNode, nil:
+		return 0, 0, false
+	}
+	tk := n.GetToken()
+	if tk.Type == token.ImplicitNullType {
+		return 0, 0, false
+	}
+	start = s.offset(tk)
+	line := s.data[start:s.lineEnd(s.line(start))]
+	switch tk.Type {
+	case token.DoubleQuoteType:
+		for i := 1; i < len(line); i++ {
+			switch line[i] {
+			case '\\':
+				i++
+			case '"':
+				return start, start + i + 1, true
+			}
+		}
+		return 0, 0, false
+	case token.SingleQuoteType:
+		for i := 1; i < len(line); i++ {
+			if line[i] == '\'' {
+				if i+1 < len(line) && line[i+1] == '\'' {
+					i++
+					continue
+				}
+				return start, start + i + 1, true
+			}
+		}
+		return 0, 0, false
+	}
+	i := 0
+	for ; i < len(line); i++ {
+		c := line[i]
+		if c == '#' && i > 0 && (line[i-1] == ' ' || line[i-1] == '\t') {
+			break
+		}
+		if inFlow && (c == ',' || c == ']' || c == '}') {
+			break
+		}
+	}
+	text := strings.TrimRight(string(line[:i]), " \t\r")
+	if _, alias := n.(*ast.AliasNode); !alias && strings.Contains(strings.TrimSpace(tk.Origin), "\n") {
+		// A plain scalar continued on the next lines
+		return 0, 0, false
+	}
+	return start, start + len(text), true
+}
+
+// resolveAliases replaces the aliases in n with the values of their anchors, so a value can be decoded
+// apart from the anchors of the rest of the document. anchors are the values of the anchors before n by name.
+func resolveAliases(n ast.Node, anchors map[string]ast.Node) ast.Node {
+	switch v := n.(type) {
+	case *ast.AliasNode:
+		if value, ok := anchors[v.Value.GetToken().Value]; ok {
+			return value
+		}
+	case *ast.AnchorNode:
+		v.Value = resolveAliases(v.Value, anchors)
+		anchors[v.Name.GetToken().Value] = v.Value
+	case *ast.TagNode:
+		v.Value = resolveAliases(v.Value, anchors)
+	case *ast.MappingNode:
+		for _, mv := range v.Values {
+			resolveAliases(mv, anchors)
+		}
+	case *ast.MappingValueNode:
+		v.Value = resolveAliases(v.Value, anchors)
+	case *ast.SequenceNode:
+		for i, value := range v.Values {
+			v.Values[i] = resolveAliases(value, anchors)
+			if i < len(v.Entries) {
+				v.Entries[i].Value = v.Values[i]
+			}
+		}
+	}
+	return n
+}
+
+// lookupMerged returns the node at steps of n, a node without aliases. Keys of mappings merged
+// with "<<" are found too, and explicit keys win over merged ones, as when decoding.
+func (s *yamlSource) lookupMerged(n ast.Node, steps []pathStep) (ast.Node, bool) {
+	if len(steps) == 0 {
+		return n, true
+	}
+	c, ok := s.collection(n)
+	if !ok || c.mapping == steps[0].isIndex {
+		return nil, false
+	}
+	if i := c.find(steps[0]); i >= 0 {
+		return s.lookupMerged(c.elements[i].value, steps[1:])
+	}
+	for _, e := range c.elements {
+		if !c.mapping || e.key != "<<" {
+			continue
+		}
+		merged := []ast.Node{e.value}
+		if seq, ok := unwrapNode(e.value).(*ast.SequenceNode); ok {
+			merged = seq.Values
+		}
+		for _, m := range merged {
+			if value, ok := s.lookupMerged(m, steps); ok {
+				return value, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// unwrapNode returns the value of anchors and tags
+func unwrapNode(n ast.Node) ast.Node {
+	for {
+		switch v := n.(type) {
+		case *ast.AnchorNode:
+			n = v.Value
+		case *ast.TagNode:
+			n = v.Value
+		default:
+			return n
+		}
+	}
+}
+
+// isNullNode reports whether n is missing or null
+func isNullNode(n ast.Node) bool {
+	_, ok := unwrapNode(n).(*ast.NullNode)
+	return n == nil || ok
+}
+
+// yamlElement is a mapping entry or sequence item in the source
+type yamlElement struct {
+	start     int // offset of the key, the "-" of a block sequence item, or the value of a flow sequence item
+	indicator int // offset after the ":" or "-", where an implicit null value would be
+	key       string
+	value     ast.Node
+}
+
+// yamlCollection is a mapping or sequence in the source
+type yamlCollection struct {
+	mapping  bool
+	flow     bool
+	end      int // offset of the closing bracket of a flow collection
+	elements []yamlElement
+}
+
+// collection returns the mapping or sequence n, or false if n is neither
+func (s *yamlSource) collection(n ast.Node) (*yamlCollection, bool) {
+	entry := func(mv *ast.MappingValueNode) yamlElement {
+		return yamlElement{
+			start:     s.offset(mv.Key.GetToken()),
+			indicator: s.offset(mv.Start) + 1,
+			key:       unwrapNode(mv.Key).GetToken().Value,
+			value:     mv.Value,
+		}
+	}
+	switch v := unwrapNode(n).(type) {
+	case *ast.MappingNode:
+		c := &yamlCollection{mapping: true, flow: v.IsFlowStyle}
+		if c.flow {
+			c.end = s.offset(v.End)
+		}
+		for _, mv := range v.Values {
+			c.elements = append(c.elements, entry(mv))
+		}
+		return c, true
+	case *ast.MappingValueNode:
+		return &yamlCollection{mapping: true, elements: []yamlElement{entry(v)}}, true
+	case *ast.SequenceNode:
+		c := &yamlCollection{flow: v.IsFlowStyle}
+		if c.flow {
+			c.end = s.offset(v.End)
+			for _, value := range v.Values {
+				c.elements = append(c.elements, yamlElement{start: s.offset(value.GetToken()), value: value})
+			}
+			return c, true
+		}
+		for _, e := range v.Entries {
+			dash := s.offset(e.Start)
+			c.elements = append(c.elements, yamlElement{start: dash, indicator: dash + 1, value: e.Value})
+		}
+		return c, true
+	}
+	return nil, false
+}
+
+// find returns the index of the element for step, or -1.
+// The last of duplicate keys wins, as when decoding.
+func (c *yamlCollection) find(step pathStep) int {
+	if step.isIndex != !c.mapping {
+		return -1
+	}
+	if step.isIndex {
+		if step.index < len(c.elements) {
+			return step.index
+		}
+		return -1
+	}
+	for i := len(c.elements) - 1; i >= 0; i-- {
+		if c.elements[i].key == step.key {
+			return i
+		}
+	}
+	return -1
+}
+
+// yamlCollectionAt returns the collection n reached by steps[:i], which must suit steps[i]
+func (s *yamlSource) yamlCollectionAt(n ast.Node, steps []pathStep, i int) (*yamlCollection, error) {
+	if _, ok := unwrapNode(n).(*ast.AliasNode); ok {
+		return nil, fmt.Errorf("path %s: cannot edit through the alias at %s", formatPath(steps), formatPath(steps[:i]))
+	}
+	if isNullNode(n) {
+		return nil, fmt.Errorf("%w: %s", ErrPathNotFound, formatPath(steps[:i+1]))
+	}
+	c, ok := s.collection(n)
+	if !ok || c.mapping == steps[i].isIndex {
+		kind := "mapping"
+		if steps[i].isIndex {
+			kind = "sequence"
+		}
+		return nil, fmt.Errorf("path %s: value at %s is not a %s", formatPath(steps), formatPath(steps[:i]), kind)
+	}
+	return c, nil
+}
+
+// lookup returns the node at steps, which must exist
+func (s *yamlSource) lookup(steps []pathStep) (ast.Node, error) {
+	n := s.body
+	for i, step := range steps {
+		c, err := s.yamlCollectionAt(n, steps, i)
+		if err != nil {
+			return nil, err
+		}
+		j := c.find(step)
+		if j < 0 {
+			return nil, fmt.Errorf("%w: %s", ErrPathNotFound, formatPath(steps[:i+1]))
+		}
+		n = c.elements[j].value
+	}
+	return n, nil
+}
+
+// setYAML sets the value at steps of a YAML document
+func (d *Document) setYAML(steps []pathStep, value interface{}) error {
+	s, err := d.parseYAMLSource()
+	if err != nil {
+		return err
+	}
+	if len(steps) == 0 {
+		return d.setYAMLRoot(s, value)
+	}
+	parentSteps, step := steps[:len(steps)-1], steps[len(steps)-1]
+	parent, err := s.lookup(parentSteps)
+	if err != nil {
+		return err
+	}
+	if !step.isIndex && isEmptyMapping(parent) {
+		return d.setYAML(parentSteps, yaml.MapSlice{{Key: step.key, Value: value}})
+	}
+	c, err := s.yamlCollectionAt(parent, steps, len(steps)-1)
+	if err != nil {
+		return err
+	}
+	if i := c.find(step); i >= 0 {
+		return d.replaceYAMLElement(s, c, i, value)
+	}
+	if step.isIndex {
+		return fmt.Errorf("%w: %s", ErrPathNotFound, formatPath(steps))
+	}
+
+	entry := yaml.MapSlice{{Key: step.key, Value: value}}
+	if c.flow {
+		text, err := d.render(entry, true)
+		if err != nil {
+			return err
+		}
+		return d.insertFlow(s, c, strings.TrimSuffix(strings.TrimPrefix(text, "{"), "}"))
+	}
+	return d.insertBlock(s, c, entry)
+}
+
+// isEmptyMapping reports whether n is null or an empty flow mapping, so a key is added by replacing it
+func isEmptyMapping(n ast.Node) bool {
+	if m, ok := unwrapNode(n).(*ast.MappingNode); ok {
+		return len(m.Values) == 0
+	}
+	return isNullNode(n)
+}
+
+// setYAMLRoot replaces the whole document
+func (d *Document) setYAMLRoot(s *yamlSource, value interface{}) error {
+	text, err := d.render(value, false)
+	if err != nil {
+		return err
+	}
+	if s.body == nil {
+		// Keep the comments of an empty document
+		if len(s.data) > 0 && s.data[len(s.data)-1] != '\n' {
+			text = "\n" + text
+		}
+		d.src = append(append([]byte(nil), s.data...), text+"\n"...)
+		return nil
+	}
+	if start, end, ok := s.valueRange(s.body, false); ok && !strings.Contains(text, "\n") {
+		d.replace(s, start, end, text)
+		return nil
+	}
+	start := s.offset(s.body.GetToken())
+	if c, ok := s.collection(s.body); ok && !c.flow && len(c.elements) > 0 {
+		start = c.elements[0].start
+	}
+	d.replace(s, start, s.blockEnd(start, -1), text)
+	return nil
+}
+
+// replaceYAMLElement replaces the value of element i of c
+func (d *Document) replaceYAMLElement(s *yamlSource, c *yamlCollection, i int, value interface{}) error {
+	e := c.elements[i]
+	text, err := d.renderScalar(e.value, value, c.flow || isFlowCollection(e.value))
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(text, "\n") && !isBlockCollection(text) {
+		if start, end, ok := s.valueRange(e.value, c.flow); ok {
+			d.replace(s, start, end, text)
+			return nil
+		}
+		if e.value.GetToken().Type == token.ImplicitNullType && !c.flow {
+			d.replace(s, e.indicator, e.indicator, " "+text)
+			return nil
+		}
+	}
+	if c.flow {
+		return fmt.Errorf("cannot write a multi-line value into a flow collection: %q", text)
+	}
+
+	// Write the whole entry or item again, keeping the text of the key
+	const placeholder = "k"
+	var element interface{} = []interface{}{value}
+	if c.mapping {
+		element = yaml.MapSlice{{Key: placeholder, Value: value}}
+	}
+	if text, err = d.render(element, false); err != nil {
+		return err
+	}
+	if c.mapping {
+		key := strings.TrimRight(string(s.data[e.start:e.indicator-1]), " ")
+		text = key + strings.TrimPrefix(text, placeholder)
+	}
+	if comment := s.lineComment(e, c.flow); comment != "" {
+		first, rest, _ := strings.Cut(text, "\n")
+		text = first + " " + comment
+		if rest != "" {
+			text += "\n" + rest
+		}
+	}
+	indent := s.column(e.start)
+	d.replace(s, e.start, s.elementEnd(e), indentRest(text, indent))
+	return nil
+}
+
+// lineComment returns the comment after the value of e on the line of its key or "-", or ""
+func (s *yamlSource) lineComment(e yamlElement, inFlow bool) string {
+	from := e.indicator
+	if _, end, ok := s.valueRange(e.value, inFlow); ok {
+		from = end
+	}
+	rest := strings.TrimSpace(string(s.data[from:s.lineEnd(s.line(from))]))
+	if !strings.HasPrefix(rest, "#") {
+		return ""
+	}
+	return rest
+}
+
+// isFlowCollection reports whether n is a mapping or sequence in flow style
+func isFlowCollection(n ast.Node) bool {
+	switch v := unwrapNode(n).(type) {
+	case *ast.MappingNode:
+		return v.IsFlowStyle
+	case *ast.SequenceNode:
+		return v.IsFlowStyle
+	}
+	return false
+}
+
+// isBlockCollection reports whether text is a mapping or sequence in block style
+func isBlockCollection(text string) bool {
+	file, err := parser.ParseBytes([]byte(text), 0)
+	if err != nil || len(file.Docs) == 0 {
+		return false
+	}
+	switch v := unwrapNode(file.Docs[0].Body).(type) {
+	case *ast.MappingValueNode:
+		return true
+	case *ast.MappingNode:
+		return !v.IsFlowStyle
+	case *ast.SequenceNode:
+		return !v.IsFlowStyle
+	}
+	return false
+}
+
+// insertFlow adds text as the last element of a flow collection
+func (d *Document) insertFlow(s *yamlSource, c *yamlCollection, text string) error {
+	if len(c.elements) == 0 {
+		d.replace(s, c.end, c.end, text)
+		return nil
+	}
+	_, end, ok := s.valueRange(c.elements[len(c.elements)-1].value, true)
+	if !ok {
+		end = c.end
+	}
+	d.replace(s, end, end, ", "+text)
+	return nil
+}
+
+// insertBlock adds element as the last element of a block collection
+func (d *Document) insertBlock(s *yamlSource, c *yamlCollection, element interface{}) error {
+	text, err := d.render(element, false)
+	if err != nil {
+		return err
+	}
+	last := c.elements[len(c.elements)-1]
+	indent := s.column(last.start)
+	at := s.elementEnd(last)
+	d.replace(s, at, at, "\n"+strings.Repeat(" ", indent)+indentRest(text, indent))
+	return nil
+}
+
+// deleteYAML removes the element at steps of a YAML document
+func (d *Document) deleteYAML(steps []pathStep) error {
+	s, err := d.parseYAMLSource()
+	if err != nil {
+		return err
+	}
+	parentSteps := steps[:len(steps)-1]
+	parent, err := s.lookup(parentSteps)
+	if err != nil {
+		return err
+	}
+	c, err := s.yamlCollectionAt(parent, steps, len(steps)-1)
+	if err != nil {
+		return err
+	}
+	i := c.find(steps[len(steps)-1])
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrPathNotFound, formatPath(steps))
+	}
+	if len(c.elements) == 1 {
+		var empty interface{} = []interface{}{}
+		if c.mapping {
+			empty = yaml.MapSlice{}
+		}
+		return d.setYAML(parentSteps, empty)
+	}
+
+	e := c.elements[i]
+	if c.flow {
+		if i+1 < len(c.elements) {
+			d.replace(s, e.start, c.elements[i+1].start, "")
+			return nil
+		}
+		_, prevEnd, ok1 := s.valueRange(c.elements[i-1].value, true)
+		_, end, ok2 := s.valueRange(e.value, true)
+		if !ok1 || !ok2 {
+			return fmt.Errorf("cannot delete a multi-line value in a flow collection at %s", formatPath(steps))
+		}
+		d.replace(s, prevEnd, end, "")
+		return nil
+	}
+	lineStart := s.lines[s.line(e.start)]
+	if strings.TrimSpace(string(s.data[lineStart:e.start])) != "" {
+		// The first entry of a mapping on the line of its "-"; the next entry moves up
+		d.replace(s, e.start, c.elements[i+1].start, "")
+		return nil
+	}
+	end := s.elementEnd(e)
+	if end < len(s.data) {
+		end++ // the newline
+	}
+	d.replace(s, lineStart, end, "")
+	return nil
+}
+
+// appendYAML adds value to the sequence at steps of a YAML document
+func (d *Document) appendYAML(steps []pathStep, value interface{}) error {
+	s, err := d.parseYAMLSource()
+	if err != nil {
+		return err
+	}
+	n, err := s.lookup(steps)
+	if errors.Is(err, ErrPathNotFound) || (err == nil && isNullNode(n)) {
+		return d.setYAML(steps, []interface{}{value})
+	}
+	if err != nil {
+		return err
+	}
+	c, ok := s.collection(n)
+	if !ok || c.mapping {
+		return fmt.Errorf("path %s: value is not a sequence", formatPath(steps))
+	}
+	if c.flow {
+		text, err := d.render(value, true)
+		if err != nil {
+			return err
+		}
+		return d.insertFlow(s, c, text)
+	}
+	return d.insertBlock(s, c, []interface{}{value})
+}
+
+// render marshals v with the options of d, without the trailing newline
+func (d *Document) render(v interface{}, flow bool) (string, error) {
+	opts := append(d.opts[:len(d.opts):len(d.opts)], withoutHeader(), withoutFooter())
+	if flow {
+		opts = append(opts, yaml.Flow(true))
+	}
+	out, err := Marshal(v, opts...)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(string(out), documentStartMarker), "\n"), nil
+}
+
+// renderScalar is like render, but keeps the quoting style of old when a string replaces a quoted string
+func (d *Document) renderScalar(old ast.Node, v interface{}, flow bool) (string, error) {
+	if s, ok := v.(string); ok && !strings.Contains(s, "\n") {
+		switch unwrapNode(old).GetToken().Type {
+		case token.DoubleQuoteType:
+			// goccy/go-yaml writes every string double-quoted in JSON mode
+			b, err := yaml.MarshalWithOptions(s, yaml.JSON())
+			return strings.TrimSuffix(string(b), "\n"), err
+		case token.SingleQuoteType:
+			return "'" + strings.ReplaceAll(s, "'", "''") + "'", nil
+		}
+	}
+	return d.render(v, flow)
+}
+
+// replace replaces the source between start and end with text
+func (d *Document) replace(s *yamlSource, start, end int, text string) {
+	src := make([]byte, 0, len(s.data)-(end-start)+len(text))
+	src = append(src, s.data[:start]...)
+	src = append(src, text...)
+	d.src = append(src, s.data[end:]...)
+}
+
+// indentRest indents the lines of text after the first, except empty lines
+func indentRest(text string, indent int) string {
+	lines := strings.Split(text, "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = strings.Repeat(" ", indent) + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
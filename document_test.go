@@ -0,0 +1,558 @@
+package yamlformat
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const documentTestYAML = `# Release configuration
+name: web # the service name
+version: "1.2.3"
+
+image:
+  repository: 'example.com/web'
+  tag: &tag v1.2.3 # keep in sync
+
+# Ports exposed by the service
+ports:
+  - 80
+  - 443
+env: [PROD, EU]
+labels: {app: web}
+extra:
+deploy:
+  - name: first
+    tag: *tag
+  - name: second
+`
+
+func TestDocumentSet(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string // documentTestYAML if empty
+		path  string
+		value interface{}
+		want  string
+	}{
+		{
+			name:  "quoted scalar",
+			path:  ".version",
+			value: "1.3.0",
+			want:  "version: \"1.3.0\"\n",
+		},
+		{
+			name:  "number-like string",
+			path:  "$.name",
+			value: "2e5",
+			want:  "name: \"2e5\" # the service name\n",
+		},
+		{
+			name:  "single-quoted scalar",
+			path:  ".image.repository",
+			value: "example.com/web's",
+			want:  "  repository: 'example.com/web''s'\n",
+		},
+		{
+			name:  "anchor and line comment",
+			path:  ".image.tag",
+			value: "v1.3.0",
+			want:  "  tag: &tag v1.3.0 # keep in sync\n",
+		},
+		{
+			name:  "plain scalar with comment",
+			path:  "$.name",
+			value: 42,
+			want:  "name: 42 # the service name\n",
+		},
+		{
+			name:  "sequence item",
+			path:  ".ports[1]",
+			value: 8443,
+			want:  "  - 80\n  - 8443\n",
+		},
+		{
+			name:  "flow sequence item",
+			path:  ".env[1]",
+			value: "US",
+			want:  "env: [PROD, US]\n",
+		},
+		{
+			name:  "flow mapping value",
+			path:  ".labels.app",
+			value: "api",
+			want:  "labels: {app: api}\n",
+		},
+		{
+			name:  "new key in flow mapping",
+			path:  ".labels.tier",
+			value: "frontend",
+			want:  "labels: {app: web, tier: frontend}\n",
+		},
+		{
+			name:  "implicit null",
+			path:  ".extra",
+			value: true,
+			want:  "extra: true\n",
+		},
+		{
+			name:  "key in null mapping",
+			path:  ".extra.debug",
+			value: true,
+			want:  "extra:\n  debug: true\ndeploy:\n",
+		},
+		{
+			name:  "scalar replaced by mapping",
+			path:  ".deploy[1].name",
+			value: map[string]string{"first": "a"},
+			want:  "  - name:\n      first: a\n",
+		},
+		{
+			name:  "new key in mapping",
+			path:  ".image.pullPolicy",
+			value: "Always",
+			want:  "  tag: &tag v1.2.3 # keep in sync\n  pullPolicy: Always\n\n# Ports",
+		},
+		{
+			name:  "new key in sequence item",
+			path:  ".deploy[0].replicas",
+			value: 2,
+			want:  "    tag: *tag\n    replicas: 2\n  - name: second\n",
+		},
+		{
+			name:  "new top-level key",
+			path:  ".owner",
+			value: []string{"a", "b"},
+			want:  "  - name: second\nowner:\n- a\n- b\n",
+		},
+		{
+			name:  "scalar with comment replaced by sequence",
+			path:  ".name",
+			value: []int{1, 2},
+			want:  "# Release configuration\nname: # the service name\n- 1\n- 2\nversion:",
+		},
+		{
+			name:  "mapping replaced by scalar",
+			path:  ".deploy[1]",
+			value: "web",
+			want:  "    tag: *tag\n  - web\n",
+		},
+		{
+			name:  "tagged scalar",
+			src:   "a: !custom \"x\" # c\n",
+			path:  ".a",
+			value: "new",
+			want:  "a: !custom \"new\" # c\n",
+		},
+		{
+			name:  "anchor and tag",
+			src:   "a: &k !!str x\nb: *k\n",
+			path:  ".a",
+			value: "new",
+			want:  "a: &k !!str new\nb: *k\n",
+		},
+		{
+			name:  "sequence at key column",
+			src:   "list:\n- a\n- b\nnext: 1\n",
+			path:  ".list",
+			value: "x",
+			want:  "list: x\nnext: 1\n",
+		},
+		{
+			name:  "entry after a sequence at key column",
+			src:   "next: 1\nlist:\n- a\n",
+			path:  ".z",
+			value: "x",
+			want:  "list:\n- a\nz: x\n",
+		},
+		{
+			name:  "flow sequence item after tags",
+			src:   "a: [!t x, !t &k \"y\", z] # c\n",
+			path:  ".a[2]",
+			value: "new",
+			want:  "a: [!t x, !t &k \"y\", new] # c\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := tt.src
+			if src == "" {
+				src = documentTestYAML
+			}
+			doc, err := ParseDocument([]byte(src), FormatYAML)
+			if err != nil {
+				t.Fatalf("ParseDocument failed: %v", err)
+			}
+			if err := doc.Set(tt.path, tt.value); err != nil {
+				t.Fatalf("Set failed: %v", err)
+			}
+			got := doc.String()
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("Set(%q) = %s\nwant it to contain %q", tt.path, got, tt.want)
+			}
+			if _, err := ParseDocument(doc.Bytes(), FormatYAML); err != nil {
+				t.Errorf("result is not valid YAML: %v\n%s", err, got)
+			}
+			var v interface{}
+			if err := doc.Get(tt.path, &v); err != nil {
+				t.Errorf("Get failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestDocumentMinimalDiff(t *testing.T) {
+	doc, err := ParseDocument([]byte(documentTestYAML), FormatYAML)
+	if err != nil {
+		t.Fatalf("ParseDocument failed: %v", err)
+	}
+	for path, value := range map[string]interface{}{".version": "2.0.0", ".image.tag": "v2.0.0"} {
+		if err := doc.Set(path, value); err != nil {
+			t.Fatalf("Set(%q) failed: %v", path, err)
+		}
+	}
+	if err := doc.Append(".ports", 8080); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if err := doc.Delete(".deploy[0].tag"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	want := `# Release configuration
+name: web # the service name
+version: "2.0.0"
+
+image:
+  repository: 'example.com/web'
+  tag: &tag v2.0.0 # keep in sync
+
+# Ports exposed by the service
+ports:
+  - 80
+  - 443
+  - 8080
+env: [PROD, EU]
+labels: {app: web}
+extra:
+deploy:
+  - name: first
+  - name: second
+`
+	if diff := cmp.Diff(want, doc.String()); diff != "" {
+		t.Errorf("document mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDocumentDelete(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		path string
+		want string
+	}{
+		{name: "entry", src: "a: 1\nb:\n  c: 2\n  d: 3\ne: 4\n", path: ".b", want: "a: 1\ne: 4\n"},
+		{name: "last entry", src: "a: 1\nb: 2", path: ".b", want: "a: 1\n"},
+		{name: "only entry", src: "a:\n  b: 1\nc: 2\n", path: ".a.b", want: "a: {}\nc: 2\n"},
+		{name: "item", src: "- a\n- b # two\n- c\n", path: "[1]", want: "- a\n- c\n"},
+		{name: "first key of item", src: "- name: a\n  size: 1\n", path: "[0].name", want: "- size: 1\n"},
+		{name: "flow item", src: "a: [1, 2, 3]\n", path: ".a[1]", want: "a: [1, 3]\n"},
+		{name: "last flow item", src: "a: [1, 2, 3]\n", path: ".a[2]", want: "a: [1, 2]\n"},
+		{name: "only flow entry", src: "a: {b: 1}\n", path: ".a.b", want: "a: {}\n"},
+		{name: "only root entry", src: "# c\na: 1\n", path: ".a", want: "# c\n{}\n"},
+		{name: "only item at key column", src: "list:\n- a\nnext: 1\n", path: ".list[0]", want: "list: []\nnext: 1\n"},
+		{name: "only item at key column at the end", src: "list:\n- a\n", path: ".list[0]", want: "list: []\n"},
+		{name: "sequence at key column", src: "list:\n- a\n- b: 1\n  c: 2\nnext: 1\n", path: ".list", want: "next: 1\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := ParseDocument([]byte(tt.src), FormatYAML)
+			if err != nil {
+				t.Fatalf("ParseDocument failed: %v", err)
+			}
+			if err := doc.Delete(tt.path); err != nil {
+				t.Fatalf("Delete failed: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, doc.String()); diff != "" {
+				t.Errorf("Delete(%q) mismatch (-want +got):\n%s", tt.path, diff)
+			}
+			var v interface{}
+			if err := Unmarshal(doc.Bytes(), &v); err != nil {
+				t.Errorf("result is not valid YAML: %v\n%s", err, doc)
+			}
+		})
+	}
+}
+
+func TestDocumentAppend(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		path  string
+		value interface{}
+		want  string
+	}{
+		{name: "block", src: "a:\n- 1\n- 2\nb: 3\n", path: ".a", value: 3, want: "a:\n- 1\n- 2\n- 3\nb: 3\n"},
+		{name: "mapping item", src: "a:\n  - name: x\n", path: ".a", value: map[string]int{"size": 1}, want: "a:\n  - name: x\n  - size: 1\n"},
+		{name: "flow", src: "a: [1, 2] # c\n", path: ".a", value: 3, want: "a: [1, 2, 3] # c\n"},
+		{name: "empty flow", src: "a: []\n", path: ".a", value: "x", want: "a: [x]\n"},
+		{name: "null", src: "a:\nb: 1\n", path: ".a", value: 1, want: "a:\n- 1\nb: 1\n"},
+		{name: "missing", src: "b: 1\n", path: ".a", value: 1, want: "b: 1\na:\n- 1\n"},
+		{name: "empty document", src: "# only a comment\n", path: ".", value: 1, want: "# only a comment\n- 1\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := ParseDocument([]byte(tt.src), FormatYAML)
+			if err != nil {
+				t.Fatalf("ParseDocument failed: %v", err)
+			}
+			if err := doc.Append(tt.path, tt.value); err != nil {
+				t.Fatalf("Append failed: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, doc.String()); diff != "" {
+				t.Errorf("Append(%q) mismatch (-want +got):\n%s", tt.path, diff)
+			}
+		})
+	}
+}
+
+func TestDocumentGet(t *testing.T) {
+	doc, err := ParseDocument([]byte(documentTestYAML), FormatYAML)
+	if err != nil {
+		t.Fatalf("ParseDocument failed: %v", err)
+	}
+	var tag string
+	if err := doc.Get(".deploy[0].tag", &tag); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if tag != "v1.2.3" {
+		t.Errorf("Get() = %q, want the aliased v1.2.3", tag)
+	}
+	var ports []int
+	if err := doc.Get(".ports", &ports); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if diff := cmp.Diff([]int{80, 443}, ports); diff != "" {
+		t.Errorf("Get() mismatch (-want +got):\n%s", diff)
+	}
+	if !doc.Has(".extra") || doc.Has(".missing") || doc.Has(".ports[2]") {
+		t.Errorf("Has() reports wrong paths")
+	}
+	if err := doc.Get(".image.digest", &tag); !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("Get() error = %v, want ErrPathNotFound", err)
+	}
+
+	doc, err = ParseDocument([]byte("a: \"new\\tval\"\nb: &x {c: 1}\nd:\n  <<: *x\n  e: *x\nb2: &x 2\nf: *x\nq: \"x\"\n"), FormatYAML)
+	if err != nil {
+		t.Fatalf("ParseDocument failed: %v", err)
+	}
+	if err := doc.Set(".q", "é\u00a0x"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	for path, want := range map[string]interface{}{
+		".a":   "new\tval",
+		".q":   "é\u00a0x",
+		".d.c": uint64(1),
+		".d.e": map[string]interface{}{"c": uint64(1)},
+		".f":   uint64(2),
+	} {
+		var got interface{}
+		if err := doc.Get(path, &got); err != nil {
+			t.Fatalf("Get(%q) failed: %v", path, err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Get(%q) mismatch (-want +got):\n%s", path, diff)
+		}
+	}
+}
+
+func TestDocumentErrors(t *testing.T) {
+	doc, err := ParseDocument([]byte("a: &x {b: 1}\nc: *x\nd: [1]\n"), FormatYAML)
+	if err != nil {
+		t.Fatalf("ParseDocument failed: %v", err)
+	}
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "missing parent", err: doc.Set(".x.y", 1), want: "path not found: .x"},
+		{name: "index out of range", err: doc.Set(".d[1]", 1), want: "path not found: .d[1]"},
+		{name: "delete missing", err: doc.Delete(".a.z"), want: "path not found: .a.z"},
+		{name: "through alias", err: doc.Set(".c.b", 2), want: "path .c.b: cannot edit through the alias at .c"},
+		{name: "not a sequence", err: doc.Append(".a", 1), want: "path .a: value is not a sequence"},
+		{name: "not a mapping", err: doc.Set(".d.k", 1), want: "path .d.k: value at .d is not a mapping"},
+		{name: "invalid path", err: doc.Set("a", 1), want: `invalid path "a": must start with "." or "["`},
+		{name: "delete root", err: doc.Delete("."), want: "cannot delete the document root"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.err == nil || tt.err.Error() != tt.want {
+				t.Errorf("error = %v, want %q", tt.err, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseDocument([]byte("a: 1\n---\nb: 2\n"), FormatYAML); err == nil {
+		t.Errorf("ParseDocument() succeeded for two documents, want error")
+	}
+	if _, err := ParseDocument([]byte("{}\n"), FormatJSONL); err == nil {
+		t.Errorf("ParseDocument() succeeded for JSON Lines, want error")
+	}
+}
+
+func TestDocumentJSON(t *testing.T) {
+	src := "{\n  \"name\": \"web\",\n  \"version\": \"1.2.3\",\n  \"ports\": [\n    80\n  ],\n  \"size\": 1.50\n}\n"
+	doc, err := ParseDocument([]byte(src), FormatJSONPretty)
+	if err != nil {
+		t.Fatalf("ParseDocument failed: %v", err)
+	}
+	if err := doc.Set(".version", "1.3.0"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := doc.Append(".ports", 443); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if err := doc.Delete(".name"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := doc.Set(".labels", map[string]string{"app": "web"}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := doc.Set(".labels.tier", "frontend"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	want := `{
+  "version": "1.3.0",
+  "ports": [
+    80,
+    443
+  ],
+  "size": 1.50,
+  "labels": {
+    "app": "web",
+    "tier": "frontend"
+  }
+}
+`
+	if diff := cmp.Diff(want, doc.String()); diff != "" {
+		t.Errorf("document mismatch (-want +got):\n%s", diff)
+	}
+	var ports []int
+	if err := doc.Get("$.ports", &ports); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if diff := cmp.Diff([]int{80, 443}, ports); diff != "" {
+		t.Errorf("Get() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDocumentJSONLayout(t *testing.T) {
+	src := "{\n    \"name\": \"web\",\n    \"tags\": [\"a\", \"b\"],\n    \"ports\": [\n        80\n    ],\n    \"size\": 1.50\n}\n"
+	doc, err := ParseDocument([]byte(src), FormatJSON)
+	if err != nil {
+		t.Fatalf("ParseDocument failed: %v", err)
+	}
+	if err := doc.Set(".name", "api"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	srcLines, gotLines := strings.Split(src, "\n"), strings.Split(doc.String(), "\n")
+	if len(gotLines) != len(srcLines) {
+		t.Fatalf("Set() changed the number of lines:\n%s", doc)
+	}
+	for i := range srcLines {
+		if i != 1 && gotLines[i] != srcLines[i] {
+			t.Errorf("Set() changed line %d: %q, want %q", i+1, gotLines[i], srcLines[i])
+		}
+	}
+
+	for _, edit := range []func() error{
+		func() error { return doc.Append(".tags", "c") },
+		func() error { return doc.Append(".ports", 443) },
+		func() error { return doc.Delete(".size") },
+		func() error { return doc.Set(".labels", map[string]string{"app": "web"}) },
+	} {
+		if err := edit(); err != nil {
+			t.Fatalf("edit failed: %v", err)
+		}
+	}
+	want := `{
+    "name": "api",
+    "tags": ["a", "b", "c"],
+    "ports": [
+        80,
+        443
+    ],
+    "labels": {
+        "app": "web"
+    }
+}
+`
+	if diff := cmp.Diff(want, doc.String()); diff != "" {
+		t.Errorf("document mismatch (-want +got):\n%s", diff)
+	}
+
+	doc, err = ParseDocument([]byte(`{"a":{"b":[1,2]},"c":true}`), FormatJSONPretty)
+	if err != nil {
+		t.Fatalf("ParseDocument failed: %v", err)
+	}
+	if err := doc.Delete(".a.b[0]"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := doc.Set(".a.d", map[string]int{"e": 1}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if diff := cmp.Diff(`{"a":{"b":[2],"d":{"e":1}},"c":true}`, doc.String()); diff != "" {
+		t.Errorf("compact document mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDocumentQuotedKeys(t *testing.T) {
+	src := "labels:\n  app.kubernetes.io/name: web # app\n  \"tier[0]\": frontend\n"
+	doc, err := ParseDocument([]byte(src), FormatYAML)
+	if err != nil {
+		t.Fatalf("ParseDocument failed: %v", err)
+	}
+	if err := doc.Set(`.labels."app.kubernetes.io/name"`, "api"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := doc.Delete(`.labels["tier[0]"]`); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if diff := cmp.Diff("labels:\n  app.kubernetes.io/name: api # app\n", doc.String()); diff != "" {
+		t.Errorf("document mismatch (-want +got):\n%s", diff)
+	}
+	if err := doc.Get(`.labels."a.b"`, new(string)); err == nil || !strings.Contains(err.Error(), `.labels."a.b"`) {
+		t.Errorf("Get() error = %v, want the quoted path", err)
+	}
+}
+
+func TestParseDocumentPath(t *testing.T) {
+	got, err := parseDocumentPath("$.spec.items[0].name")
+	if err != nil {
+		t.Fatalf("parseDocumentPath failed: %v", err)
+	}
+	want := []pathStep{{key: "spec"}, {key: "items"}, {index: 0, isIndex: true}, {key: "name"}}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(pathStep{})); diff != "" {
+		t.Errorf("parseDocumentPath() mismatch (-want +got):\n%s", diff)
+	}
+	got, err = parseDocumentPath(`."a.b"["c[0]"].["d\"e"][1]`)
+	if err != nil {
+		t.Fatalf("parseDocumentPath failed: %v", err)
+	}
+	want = []pathStep{{key: "a.b"}, {key: "c[0]"}, {key: `d"e`}, {index: 1, isIndex: true}}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(pathStep{})); diff != "" {
+		t.Errorf("parseDocumentPath() mismatch (-want +got):\n%s", diff)
+	}
+	if got, want := formatPath(got), `."a.b"."c[0]"."d\"e"[1]`; got != want {
+		t.Errorf("formatPath() = %s, want %s", got, want)
+	}
+	for _, path := range []string{".a..b", ".a[x]", ".a[-1]", ".a[0", "a", `."a`, `["a"`, `."\x"`} {
+		if _, err := parseDocumentPath(path); err == nil {
+			t.Errorf("parseDocumentPath(%q) succeeded, want error", path)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package yamlformat
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// documentStartMarker is the marker written before a YAML document
+const documentStartMarker = "---\n"
+
+// ExplicitDocumentStart writes a "---" marker before the first YAML document too,
+// not only between documents. It applies to Marshal, NewEncoder and MarshalAll; JSON output ignores it.
+func ExplicitDocumentStart() yaml.EncodeOption {
+	return settingOption(func(p documentStartProbe) { p.s.documentStart = true })
+}
+
+// documentStartWriter writes a header comment, and a document start marker if marker is set,
+// before the first document written by yaml.Encoder
+type documentStartWriter struct {
+	w       io.Writer
+	header  string
+	marker  bool
+	started bool
+}
+
+func (dw *documentStartWriter) Write(p []byte) (int, error) {
+	if !dw.started {
+		dw.started = true
+		start := dw.header
+		if dw.marker && string(p) != documentStartMarker {
+			start += documentStartMarker
+		}
+		if _, err := io.WriteString(dw.w, start); err != nil {
+			return 0, err
+		}
+	}
+	return dw.w.Write(p)
+}
+
+// MarshalAll marshals values into a YAML stream with one document per value, separated by "---".
+// An empty values writes nothing.
+func MarshalAll(values []interface{}, opts ...yaml.EncodeOption) ([]byte, error) {
+	var buf bytes.Buffer
+	// The footer comment is written once, after the last document
+	footer := loadEncodeSettings(opts).documentSuffix()
+	opts = append(opts[:len(opts):len(opts)], withoutFooter())
+	for i, v := range values {
+		if i == 1 {
+			// The header comment is written once, before the first document
+			opts = append(opts, withoutHeader())
+		}
+		// Marshal each value, rather than use an Encoder, so the key order and struct tag comments see the values
+		b, err := Marshal(v, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %w", i, err)
+		}
+		if i > 0 && !bytes.HasPrefix(b, []byte(documentStartMarker)) {
+			buf.WriteString(documentStartMarker)
+		}
+		buf.Write(b)
+	}
+	if len(values) > 0 {
+		buf.WriteString(footer)
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalAll unmarshals each document of a YAML stream into a new value from newValue,
+// which must return a pointer (e.g. func() interface{} { return new(Manifest) }), and returns the values.
+// If newValue is nil, documents are unmarshaled into *interface{} values.
+// Documents are separated by "---" and may be ended by "..."; empty documents between two markers are kept.
+func UnmarshalAll(data []byte, newValue func() interface{}, opts ...yaml.DecodeOption) ([]interface{}, error) {
+	if newValue == nil {
+		newValue = func() interface{} { return new(interface{}) }
+	}
+	var values []interface{}
+	dec := NewDocumentDecoder(bytes.NewReader(data), opts...)
+	for dec.Next() {
+		v := newValue()
+		if err := dec.Decode(v); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	if err := dec.Err(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// DocumentDecoder iterates over the documents of a YAML stream, reading one document at a time.
+// Use it like bufio.Scanner:
+//
+//	dec := yamlformat.NewDocumentDecoder(r)
+//	for dec.Next() {
+//		var m Manifest
+//		if err := dec.Decode(&m); err != nil {
+//			return err
+//		}
+//	}
+//	if err := dec.Err(); err != nil {
+//		return err
+//	}
+type DocumentDecoder struct {
+	r       *bufio.Reader
+	opts    []yaml.DecodeOption
+	doc     []byte
+	index   int
+	pending string // a "---" line that starts the next document
+	err     error
+}
+
+// NewDocumentDecoder creates a DocumentDecoder that reads from r and decodes with consistent options
+func NewDocumentDecoder(r io.Reader, opts ...yaml.DecodeOption) *DocumentDecoder {
+	return &DocumentDecoder{r: bufio.NewReader(r), opts: opts, index: -1}
+}
+
+// Next advances to the next document. It returns false at the end of the stream or on a read error.
+func (d *DocumentDecoder) Next() bool {
+	if d.err != nil {
+		return false
+	}
+	doc, err := d.readDocument()
+	if err != nil {
+		if !errors.Is(err, io.EOF) {
+			d.err = err
+		}
+		d.doc = nil
+		return false
+	}
+	d.doc = doc
+	d.index++
+	return true
+}
+
+// Decode unmarshals the current document into v. It may be called more than once per document,
+// e.g. to read the kind of a manifest before decoding it into the matching type.
+func (d *DocumentDecoder) Decode(v interface{}) error {
+	if d.doc == nil {
+		return errors.New("no current document: call Next first")
+	}
+	if err := Unmarshal(d.doc, v, d.opts...); err != nil {
+		return fmt.Errorf("document %d: %w", d.index, err)
+	}
+	return nil
+}
+
+// Bytes returns the source of the current document, including its "---" marker if any
+func (d *DocumentDecoder) Bytes() []byte {
+	return d.doc
+}
+
+// Index returns the zero-based index of the current document
+func (d *DocumentDecoder) Index() int {
+	return d.index
+}
+
+// Err returns the first read error, or nil at the end of the stream
+func (d *DocumentDecoder) Err() error {
+	return d.err
+}
+
+// readDocument reads the lines of the next document.
+// Document markers are only recognized at the start of a line, where YAML forbids them inside content.
+// Comments and directives before the first content belong to the document, and a document that has only
+// comments is skipped unless it has a "---" marker.
+func (d *DocumentDecoder) readDocument() ([]byte, error) {
+	var buf []byte
+	hasMarker, hasContent := false, false
+	if d.pending != "" {
+		buf, hasMarker, hasContent = []byte(d.pending), true, markerHasContent(d.pending)
+		d.pending = ""
+	}
+	for {
+		line, err := d.r.ReadString('\n')
+		if line == "" && err != nil {
+			if errors.Is(err, io.EOF) && (hasMarker || hasContent) {
+				return buf, nil
+			}
+			return nil, err
+		}
+		switch {
+		case isDocumentMarker(line, "---"):
+			if hasMarker || hasContent {
+				d.pending = line
+				return buf, nil
+			}
+			buf, hasMarker, hasContent = append(buf, line...), true, markerHasContent(line)
+		case isDocumentMarker(line, "..."):
+			if hasMarker || hasContent {
+				return buf, nil
+			}
+			buf = nil
+		default:
+			buf = append(buf, line...)
+			if !hasContent && !isIgnorableLine(line, hasMarker) {
+				hasContent = true
+			}
+		}
+	}
+}
+
+// isDocumentMarker reports whether line is a "---" or "..." marker
+func isDocumentMarker(line, marker string) bool {
+	if !strings.HasPrefix(line, marker) {
+		return false
+	}
+	rest := line[len(marker):]
+	return rest == "" || strings.ContainsAny(rest[:1], " \t\r\n")
+}
+
+// markerHasContent reports whether a "---" line has content after the marker (e.g. "--- |")
+func markerHasContent(line string) bool {
+	return !isIgnorableLine(line[len("---"):], true)
+}
+
+// isIgnorableLine reports whether line is blank, a comment, or a directive before the "---" marker
+func isIgnorableLine(line string, afterMarker bool) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed == "" || strings.HasPrefix(trimmed, "#") || (!afterMarker && strings.HasPrefix(line, "%"))
+}
+
+// MarshalAll is like MarshalAll but uses o instead of the package defaults
+func (o Options) MarshalAll(values []interface{}, opts ...yaml.EncodeOption) ([]byte, error) {
+	return MarshalAll(values, o.encodeOptions(opts)...)
+}
+
+// UnmarshalAll is like UnmarshalAll but uses o instead of the package defaults
+func (o Options) UnmarshalAll(data []byte, newValue func() interface{}, opts ...yaml.DecodeOption) ([]interface{}, error) {
+	return UnmarshalAll(data, newValue, o.decodeOptions(opts)...)
+}
+
+// NewDocumentDecoder is like NewDocumentDecoder but uses o instead of the package defaults
+func (o Options) NewDocumentDecoder(r io.Reader, opts ...yaml.DecodeOption) *DocumentDecoder {
+	return NewDocumentDecoder(r, o.decodeOptions(opts)...)
+}
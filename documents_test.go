@@ -0,0 +1,192 @@
+package yamlformat
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type documentsTestManifest struct {
+	Kind string `yaml:"kind"`
+	Name string `yaml:"name"`
+}
+
+func TestMarshalAll(t *testing.T) {
+	values := []interface{}{
+		documentsTestManifest{Kind: "Service", Name: "web"},
+		documentsTestManifest{Kind: "Deployment", Name: "web"},
+	}
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{
+			name: "default",
+			opts: DefaultOptions(),
+			want: "kind: Service\nname: web\n---\nkind: Deployment\nname: web\n",
+		},
+		{
+			name: "explicit document start",
+			opts: DefaultOptions().With(ExplicitDocumentStart()),
+			want: "---\nkind: Service\nname: web\n---\nkind: Deployment\nname: web\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.opts.MarshalAll(values)
+			if err != nil {
+				t.Fatalf("MarshalAll failed: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, string(got)); diff != "" {
+				t.Errorf("MarshalAll() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	got, err := MarshalAll(nil)
+	if err != nil || len(got) != 0 {
+		t.Errorf("MarshalAll(nil) = %q, %v, want empty output", got, err)
+	}
+}
+
+func TestExplicitDocumentStart(t *testing.T) {
+	got, err := Marshal(map[string]int{"a": 1}, ExplicitDocumentStart())
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if want := "---\na: 1\n"; string(got) != want {
+		t.Errorf("Marshal() = %q, want %q", got, want)
+	}
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf, ExplicitDocumentStart())
+	for _, v := range []int{1, 2} {
+		if err := enc.Encode(v); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+	if want := "---\n1\n---\n2\n"; buf.String() != want {
+		t.Errorf("Encode() wrote %q, want %q", buf.String(), want)
+	}
+
+	got, err = MarshalJSON(1, ExplicitDocumentStart())
+	if err != nil {
+		t.Fatalf("MarshalJSON failed: %v", err)
+	}
+	if want := "1\n"; string(got) != want {
+		t.Errorf("MarshalJSON() = %q, want %q", got, want)
+	}
+}
+
+func TestUnmarshalAll(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []interface{}
+	}{
+		{
+			name:  "separators",
+			input: "a: 1\n---\nb: 2\n",
+			want:  []interface{}{map[string]interface{}{"a": uint64(1)}, map[string]interface{}{"b": uint64(2)}},
+		},
+		{
+			name:  "leading marker and comments",
+			input: "# bundle\n---\n# first\na: 1\n--- # second\nb: 2\n",
+			want:  []interface{}{map[string]interface{}{"a": uint64(1)}, map[string]interface{}{"b": uint64(2)}},
+		},
+		{
+			name:  "document end markers",
+			input: "a: 1\n...\n# between\n...\nb: 2\n...\n",
+			want:  []interface{}{map[string]interface{}{"a": uint64(1)}, map[string]interface{}{"b": uint64(2)}},
+		},
+		{
+			name:  "directive",
+			input: "%YAML 1.2\n---\na: 1\n",
+			want:  []interface{}{map[string]interface{}{"a": uint64(1)}},
+		},
+		{
+			name:  "empty document between markers",
+			input: "---\n---\na: 1\n",
+			want:  []interface{}{nil, map[string]interface{}{"a": uint64(1)}},
+		},
+		{
+			name:  "content on marker line",
+			input: "--- 1\n--- |\n  text\n",
+			want:  []interface{}{uint64(1), "text\n"},
+		},
+		{
+			name:  "markers inside content",
+			input: "a: \"--- not a marker\"\nb: |\n  ---\n  ...\n",
+			want:  []interface{}{map[string]interface{}{"a": "--- not a marker", "b": "---\n...\n"}},
+		},
+		{
+			name:  "empty",
+			input: "# nothing\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := UnmarshalAll([]byte(tt.input), nil)
+			if err != nil {
+				t.Fatalf("UnmarshalAll failed: %v", err)
+			}
+			var got []interface{}
+			for _, v := range values {
+				got = append(got, *v.(*interface{}))
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("UnmarshalAll() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUnmarshalAllNewValue(t *testing.T) {
+	input := "kind: Service\nname: web\n---\nkind: Deployment\nname: api\n"
+	values, err := UnmarshalAll([]byte(input), func() interface{} { return new(documentsTestManifest) })
+	if err != nil {
+		t.Fatalf("UnmarshalAll failed: %v", err)
+	}
+	want := []interface{}{
+		&documentsTestManifest{Kind: "Service", Name: "web"},
+		&documentsTestManifest{Kind: "Deployment", Name: "api"},
+	}
+	if diff := cmp.Diff(want, values); diff != "" {
+		t.Errorf("UnmarshalAll() mismatch (-want +got):\n%s", diff)
+	}
+
+	_, err = UnmarshalAll([]byte("a: 1\n---\na: [1\n"), nil)
+	if err == nil || !strings.HasPrefix(err.Error(), "document 1: ") {
+		t.Errorf("UnmarshalAll() error = %v, want an error for document 1", err)
+	}
+}
+
+func TestDocumentDecoder(t *testing.T) {
+	input := "kind: Service\nname: web\n---\nkind: Deployment\nname: api\n"
+	dec := NewDocumentDecoder(strings.NewReader(input))
+	var kinds []string
+	for dec.Next() {
+		var m documentsTestManifest
+		if err := dec.Decode(&m); err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		if dec.Index() != len(kinds) {
+			t.Errorf("Index() = %d, want %d", dec.Index(), len(kinds))
+		}
+		kinds = append(kinds, m.Kind)
+	}
+	if err := dec.Err(); err != nil {
+		t.Fatalf("Err() = %v", err)
+	}
+	if diff := cmp.Diff([]string{"Service", "Deployment"}, kinds); diff != "" {
+		t.Errorf("kinds mismatch (-want +got):\n%s", diff)
+	}
+	if err := dec.Decode(new(interface{})); err == nil {
+		t.Error("Decode() after the last document succeeded, want error")
+	}
+}
@@ -0,0 +1,317 @@
+package yamlformat
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/goccy/go-yaml/token"
+)
+
+// NumberPolicy controls the Go types of numbers decoded into interface{} values,
+// including values nested in map[string]interface{} and []interface{}
+type NumberPolicy int
+
+const (
+	// NumberDefault keeps the goccy/go-yaml types: uint64 for non-negative integers,
+	// int64 for negative integers and float64 for floats (default).
+	// goccy/go-yaml reads exponents without a fraction such as 1e3, and integers beyond uint64, as strings;
+	// in JSON input decoded by UnmarshalJSON or the JSON decoders they are float64, as with encoding/json.
+	NumberDefault NumberPolicy = iota
+	// NumberInt64Float64 decodes integers as int64 and floats as float64.
+	// Integers outside the range of int64 are decoded as float64, like encoding/json does for all numbers,
+	// so they may lose precision; use NumberJSONNumber to keep them exactly.
+	NumberInt64Float64
+	// NumberAutoInt is like NumberInt64Float64, but also decodes whole floats such as 100.0 as int64.
+	// It mirrors yaml.AutoInt on the encoding side, so decode and encode round-trips are stable.
+	NumberAutoInt
+	// NumberJSONNumber decodes numbers as json.Number, keeping integers of any size exactly
+	NumberJSONNumber
+)
+
+// DecodeNumbers sets the Go types of numbers decoded into interface{} values.
+// Any policy but NumberDefault decodes mappings in interface{} values as map[string]interface{}
+// (or *OrderedMap with DecodeOrderedMaps), so yaml.UseOrderedMap has no effect on them.
+func DecodeNumbers(policy NumberPolicy) yaml.DecodeOption {
+	return decodeSettingOption(func(p numbersProbe) { p.s.numbers = policy })
+}
+
+// interfaceUnmarshaler returns a custom unmarshaler for interface{} values that applies the settings,
+// or nil if the settings keep the goccy/go-yaml defaults
+func interfaceUnmarshaler(s decodeSettings) yaml.DecodeOption {
+	if s.numbers == NumberDefault && !s.ordered && !s.orderedMaps {
+		if !s.json {
+			return nil
+		}
+		// Only the numbers of JSON input differ from goccy/go-yaml, so mappings keep its type
+		s.ordered = s.useOrderedMap
+	}
+	return yaml.CustomUnmarshaler[interface{}](func(v *interface{}, b []byte) error {
+		// b is a self-contained document because goccy/go-yaml resolves aliases before calling unmarshalers
+		file, err := parser.ParseBytes(b, 0, s.parseOptions()...)
+		if err != nil {
+			return err
+		}
+		if len(file.Docs) == 0 || file.Docs[0].Body == nil {
+			*v = nil
+			return nil
+		}
+		value, err := s.nodeToValue(file.Docs[0].Body)
+		if err != nil {
+			return err
+		}
+		*v = value
+		return nil
+	})
+}
+
+// parseOptions returns the parser options for the settings
+func (s decodeSettings) parseOptions() []parser.Option {
+	if s.json {
+		return []parser.Option{parser.AllowDuplicateMapKey()}
+	}
+	return nil
+}
+
+// nodeToValue converts node into a dynamic value according to the settings
+func (s decodeSettings) nodeToValue(node ast.Node) (interface{}, error) {
+	switch n := node.(type) {
+	case *ast.IntegerNode:
+		return s.integer(n), nil
+	case *ast.FloatNode:
+		return s.float(n.Value, n.Token.Value), nil
+	case *ast.StringNode:
+		// Plain integers too large for uint64 and exponents without a fraction such as 1e3 are string nodes
+		if n.Token.Type == token.StringType && (s.numbers != NumberDefault || s.json) && isJSONNumber(n.Value) {
+			return s.numberLiteral(n.Value)
+		}
+		return n.Value, nil
+	case *ast.AnchorNode:
+		return s.nodeToValue(n.Value)
+	case *ast.SequenceNode:
+		values := make([]interface{}, 0, len(n.Values))
+		for _, child := range n.Values {
+			value, err := s.nodeToValue(child)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	case *ast.MappingNode:
+		return s.mappingToValue(n.Values)
+	case *ast.MappingValueNode:
+		return s.mappingToValue([]*ast.MappingValueNode{n})
+	}
+	// Leave other nodes (tags, ...) to goccy/go-yaml and normalize the numbers afterwards
+	var value interface{}
+	if err := yaml.NodeToValue(node, &value); err != nil {
+		return nil, err
+	}
+	return s.normalize(value), nil
+}
+
+// mappingToValue converts mapping values into map[string]interface{}, or *OrderedMap or yaml.MapSlice
+// in source order if the settings keep the order. Merge keys are expanded: explicit keys take precedence over merged ones,
+// and earlier merged mappings over later ones.
+func (s decodeSettings) mappingToValue(values []*ast.MappingValueNode) (interface{}, error) {
+	var keys []string
+	m := make(map[string]interface{}, len(values))
+	set := func(key string, value interface{}, override bool) {
+		if _, ok := m[key]; !ok {
+			keys = append(keys, key)
+		} else if !override {
+			return
+		}
+		m[key] = value
+	}
+	for _, mv := range values {
+		if mv.Key.IsMergeKey() {
+			if err := s.merge(mv.Value, func(key string, value interface{}) { set(key, value, false) }); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		key, err := mappingKeyToString(mv.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := s.nodeToValue(mv.Value)
+		if err != nil {
+			return nil, err
+		}
+		set(key, value, true)
+	}
+	if s.orderedMaps {
+		return &OrderedMap{keys: keys, values: m}, nil
+	}
+	if !s.ordered {
+		return m, nil
+	}
+	ordered := make(yaml.MapSlice, len(keys))
+	for i, key := range keys {
+		ordered[i] = yaml.MapItem{Key: key, Value: m[key]}
+	}
+	return ordered, nil
+}
+
+// merge calls set for each key of the mapping, or sequence of mappings, that is the value of a merge key
+func (s decodeSettings) merge(node ast.Node, set func(key string, value interface{})) error {
+	switch n := node.(type) {
+	case *ast.AnchorNode:
+		return s.merge(n.Value, set)
+	case *ast.SequenceNode:
+		for _, child := range n.Values {
+			if err := s.merge(child, set); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *ast.MappingNode, *ast.MappingValueNode:
+		value, err := s.nodeToValue(n)
+		if err != nil {
+			return err
+		}
+		switch v := value.(type) {
+		case *OrderedMap:
+			for _, key := range v.keys {
+				set(key, v.values[key])
+			}
+		case yaml.MapSlice:
+			for _, item := range v {
+				set(item.Key.(string), item.Value)
+			}
+		case map[string]interface{}:
+			// Keys of a map have no order; sort them so the result is deterministic
+			keys := make([]string, 0, len(v))
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				set(key, v[key])
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("merge key value must be a mapping or a sequence of mappings, got %s", node.Type())
+	}
+}
+
+// mappingKeyToString converts a mapping key to a string like goccy/go-yaml does for interface{} values
+func mappingKeyToString(key ast.MapKeyNode) (string, error) {
+	if s, ok := key.(*ast.StringNode); ok {
+		return s.Value, nil
+	}
+	var k interface{}
+	if err := yaml.NodeToValue(key, &k); err != nil {
+		return "", err
+	}
+	if k == nil {
+		return "null", nil
+	}
+	return fmt.Sprint(k), nil
+}
+
+func (s decodeSettings) integer(n *ast.IntegerNode) interface{} {
+	if s.numbers == NumberDefault {
+		return n.Value
+	}
+	switch v := n.Value.(type) {
+	case int64:
+		if s.numbers == NumberJSONNumber {
+			return json.Number(strconv.FormatInt(v, 10))
+		}
+		return v
+	case uint64:
+		if s.numbers == NumberJSONNumber {
+			return json.Number(strconv.FormatUint(v, 10))
+		}
+		if v <= math.MaxInt64 {
+			return int64(v)
+		}
+		return float64(v)
+	default:
+		return v
+	}
+}
+
+// numberLiteral converts a number literal in JSON syntax that goccy/go-yaml decodes as a string
+func (s decodeSettings) numberLiteral(literal string) (interface{}, error) {
+	if s.numbers == NumberJSONNumber {
+		return json.Number(literal), nil
+	}
+	if v, err := strconv.ParseInt(literal, 10, 64); err == nil {
+		return v, nil
+	}
+	v, err := strconv.ParseFloat(literal, 64)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode number %s: %w", literal, err)
+	}
+	return s.float(v, literal), nil
+}
+
+func (s decodeSettings) float(v float64, literal string) interface{} {
+	switch s.numbers {
+	case NumberJSONNumber:
+		if isJSONNumber(literal) {
+			return json.Number(literal)
+		}
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return v
+		}
+		return json.Number(strconv.FormatFloat(v, 'g', -1, 64))
+	case NumberAutoInt:
+		if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+			return int64(v)
+		}
+	}
+	return v
+}
+
+// normalize applies the number policy to a value decoded by goccy/go-yaml
+func (s decodeSettings) normalize(value interface{}) interface{} {
+	if s.numbers == NumberDefault {
+		return value
+	}
+	switch v := value.(type) {
+	case uint64:
+		if s.numbers == NumberJSONNumber {
+			return json.Number(strconv.FormatUint(v, 10))
+		}
+		if v <= math.MaxInt64 {
+			return int64(v)
+		}
+		return float64(v)
+	case int64:
+		if s.numbers == NumberJSONNumber {
+			return json.Number(strconv.FormatInt(v, 10))
+		}
+	case int:
+		return s.normalize(int64(v))
+	case float64:
+		return s.float(v, "")
+	case map[string]interface{}:
+		for k, child := range v {
+			v[k] = s.normalize(child)
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = s.normalize(child)
+		}
+	}
+	return value
+}
+
+// isJSONNumber reports whether s is a number literal in JSON syntax
+func isJSONNumber(s string) bool {
+	if s == "" || (s[0] != '-' && (s[0] < '0' || s[0] > '9')) {
+		return false
+	}
+	return json.Valid([]byte(s))
+}
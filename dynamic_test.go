@@ -0,0 +1,181 @@
+package yamlformat
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDecodeNumbers(t *testing.T) {
+	const yamlInput = "int: 42\nneg: -7\nwhole: 100.0\nfloat: 1.10\nbig: 18446744073709551615\nhuge: 123456789012345678901234567890\nlist: [1, 2.5]\nnested: {n: 3}\nexp: 1e3\nfrac: 2.5e-3\n"
+	const jsonInput = `{"int": 42, "neg": -7, "whole": 100.0, "float": 1.10, "big": 18446744073709551615, "huge": 123456789012345678901234567890, "list": [1, 2.5], "nested": {"n": 3}, "exp": 1e3, "frac": 2.5e-3}`
+
+	tests := []struct {
+		name   string
+		policy NumberPolicy
+		want   map[string]interface{}
+		// wantJSON is the result for JSON input if it differs from want
+		wantJSON map[string]interface{}
+	}{
+		{
+			name:   "default",
+			policy: NumberDefault,
+			want: map[string]interface{}{
+				"int": uint64(42), "neg": int64(-7), "whole": 100.0, "float": 1.1,
+				"big": uint64(18446744073709551615), "huge": "123456789012345678901234567890",
+				"list": []interface{}{uint64(1), 2.5}, "nested": map[string]interface{}{"n": uint64(3)},
+				"exp": "1e3", "frac": 0.0025,
+			},
+			// JSON numbers are never strings, as with encoding/json
+			wantJSON: map[string]interface{}{
+				"int": uint64(42), "neg": int64(-7), "whole": 100.0, "float": 1.1,
+				"big": uint64(18446744073709551615), "huge": float64(123456789012345678901234567890),
+				"list": []interface{}{uint64(1), 2.5}, "nested": map[string]interface{}{"n": uint64(3)},
+				"exp": 1000.0, "frac": 0.0025,
+			},
+		},
+		{
+			name:   "int64 and float64",
+			policy: NumberInt64Float64,
+			want: map[string]interface{}{
+				"int": int64(42), "neg": int64(-7), "whole": 100.0, "float": 1.1,
+				"big": float64(18446744073709551615), "huge": float64(123456789012345678901234567890),
+				"list": []interface{}{int64(1), 2.5}, "nested": map[string]interface{}{"n": int64(3)},
+				"exp": 1000.0, "frac": 0.0025,
+			},
+		},
+		{
+			name:   "auto int",
+			policy: NumberAutoInt,
+			want: map[string]interface{}{
+				"int": int64(42), "neg": int64(-7), "whole": int64(100), "float": 1.1,
+				"big": float64(18446744073709551615), "huge": float64(123456789012345678901234567890),
+				"list": []interface{}{int64(1), 2.5}, "nested": map[string]interface{}{"n": int64(3)},
+				"exp": int64(1000), "frac": 0.0025,
+			},
+		},
+		{
+			name:   "json.Number",
+			policy: NumberJSONNumber,
+			want: map[string]interface{}{
+				"int": json.Number("42"), "neg": json.Number("-7"), "whole": json.Number("100.0"), "float": json.Number("1.10"),
+				"big": json.Number("18446744073709551615"), "huge": json.Number("123456789012345678901234567890"),
+				"list": []interface{}{json.Number("1"), json.Number("2.5")}, "nested": map[string]interface{}{"n": json.Number("3")},
+				"exp": json.Number("1e3"), "frac": json.Number("2.5e-3"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		inputs := []struct {
+			name      string
+			data      string
+			unmarshal func(data []byte, v interface{}, opts ...yaml.DecodeOption) error
+			want      map[string]interface{}
+		}{
+			{"YAML", yamlInput, Unmarshal, tt.want},
+			{"JSON", jsonInput, UnmarshalJSON, tt.wantJSON},
+		}
+		for _, input := range inputs {
+			t.Run(tt.name+"/"+input.name, func(t *testing.T) {
+				want := input.want
+				if want == nil {
+					want = tt.want
+				}
+				var got map[string]interface{}
+				if err := input.unmarshal([]byte(input.data), &got, DecodeNumbers(tt.policy)); err != nil {
+					t.Fatalf("Unmarshal failed: %v", err)
+				}
+				if diff := cmp.Diff(want, got); diff != "" {
+					t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+				}
+			})
+		}
+	}
+}
+
+func TestDecodeNumbersInterface(t *testing.T) {
+	type record struct {
+		Count int         `yaml:"count"`
+		Any   interface{} `yaml:"any"`
+	}
+
+	input := "count: 1\nany:\n  base: &b {x: 1.0}\n  merged:\n    <<: *b\n    y: 2\n  tagged: !!float 3\n"
+	var got record
+	if err := Unmarshal([]byte(input), &got, DecodeNumbers(NumberAutoInt)); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	want := record{
+		Count: 1,
+		Any: map[string]interface{}{
+			"base":   map[string]interface{}{"x": int64(1)},
+			"merged": map[string]interface{}{"x": int64(1), "y": int64(2)},
+			"tagged": int64(3),
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDecodeNumbersDecoder(t *testing.T) {
+	decoder := NewJSONLDecoder(strings.NewReader("1\n2.0\n"), DecodeNumbers(NumberAutoInt))
+
+	var got []interface{}
+	for i := 0; i < 2; i++ {
+		var v interface{}
+		if err := decoder.Decode(&v); err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		got = append(got, v)
+	}
+	if diff := cmp.Diff([]interface{}{int64(1), int64(2)}, got); diff != "" {
+		t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDecodeNumbersRoundTrip(t *testing.T) {
+	// Decoding with NumberAutoInt and encoding with the defaults gives the same output for YAML and JSON input
+	for _, input := range []string{"a: 1.0\nb: 2.5\nc: 3\n", `{"a": 1.0, "b": 2.5, "c": 3}`} {
+		var v interface{}
+		if err := Unmarshal([]byte(input), &v, DecodeNumbers(NumberAutoInt)); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		got, err := Marshal(v)
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		if want := "a: 1\nb: 2.5\nc: 3\n"; string(got) != want {
+			t.Errorf("Marshal(Unmarshal(%q)) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestWithUnmarshalOptionsDecodeNumbers(t *testing.T) {
+	var got interface{}
+	opts := WithUnmarshalOptions(DecodeNumbers(NumberInt64Float64))
+	if err := yaml.UnmarshalWithOptions([]byte("[1]"), &got, opts...); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if diff := cmp.Diff([]interface{}{int64(1)}, got); diff != "" {
+		t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDecodeNumbersJSONNumberRoundTrip(t *testing.T) {
+	input := `{"big": 123456789012345678901234567890, "float": 0.10000000000000000555, "int": -1}`
+	var v interface{}
+	if err := UnmarshalJSON([]byte(input), &v, DecodeNumbers(NumberJSONNumber)); err != nil {
+		t.Fatalf("UnmarshalJSON failed: %v", err)
+	}
+	got, err := MarshalJSONCompact(v)
+	if err != nil {
+		t.Fatalf("MarshalJSONCompact failed: %v", err)
+	}
+	if want := `{"big":123456789012345678901234567890,"float":0.10000000000000000555,"int":-1}`; string(got) != want {
+		t.Errorf("MarshalJSONCompact() = %q, want %q", got, want)
+	}
+}
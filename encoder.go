@@ -0,0 +1,262 @@
+package yamlformat
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/goccy/go-yaml"
+)
+
+// StreamMode controls how an Encoder writes a stream of values
+type StreamMode int
+
+const (
+	// StreamDocuments writes one document per value: YAML documents separated by "---",
+	// JSON values one after another, or one JSON Lines record per line (default)
+	StreamDocuments StreamMode = iota
+	// StreamArray writes all values as the elements of a single JSON array or YAML sequence,
+	// which is completed by Close
+	StreamArray
+)
+
+// ErrEncoderClosed is returned by Encoder.Encode after Close
+var ErrEncoderClosed = errors.New("encoder is closed")
+
+// Encoder writes a stream of values in a format. Unlike yaml.Encoder, it always produces valid output:
+// in StreamArray mode, Close writes the end of the array, so Close must be called after the last value.
+// Each value is marshaled like Marshal, so the key order and struct tag comments see it,
+// and errors from marshaling and writing are returned by Encode.
+// The output of NewStreamEncoder is buffered, and Flush writes it to the underlying writer;
+// the encoders of NewJSONCompactEncoder, NewJSONIndentEncoder and NewJSONLEncoder write each value as it is encoded.
+type Encoder struct {
+	w      *bufio.Writer
+	format Format
+	mode   StreamMode
+	opts   []yaml.EncodeOption
+	err    error // set by an invalid format
+	// unbuffered flushes w after each value
+	unbuffered bool
+
+	enc    ValueEncoder  // StreamDocuments of a registered format
+	layout *streamLayout // a built-in format
+	values []interface{} // StreamArray of a registered format, written by Close
+	count  int
+	closed bool
+}
+
+// NewStreamEncoder creates an Encoder that writes values in format to w.
+// An unknown format is reported by Encode and Close.
+func NewStreamEncoder(w io.Writer, format Format, mode StreamMode, opts ...yaml.EncodeOption) *Encoder {
+	e := &Encoder{w: bufio.NewWriter(w), format: format, mode: mode, opts: opts}
+	if e.err = format.Validate(); e.err != nil {
+		return e
+	}
+	if mode == StreamArray {
+		e.layout = newArrayLayout(format, opts)
+	} else if e.layout = newDocumentsLayout(format, opts); e.layout == nil {
+		e.enc = format.spec().NewEncoder(e.w, opts...)
+	}
+	return e
+}
+
+// newValueEncoder returns an unbuffered Encoder that writes the values of a stream to w in layout
+func newValueEncoder(w io.Writer, layout *streamLayout) *Encoder {
+	return &Encoder{w: bufio.NewWriter(w), layout: layout, unbuffered: true}
+}
+
+// NewStreamEncoder is like NewStreamEncoder but uses o instead of the package defaults
+func (o Options) NewStreamEncoder(w io.Writer, format Format, mode StreamMode, opts ...yaml.EncodeOption) *Encoder {
+	return NewStreamEncoder(w, format, mode, o.encodeOptions(opts)...)
+}
+
+// Encode writes v to the stream
+func (e *Encoder) Encode(v interface{}) error {
+	switch {
+	case e.err != nil:
+		return e.err
+	case e.closed:
+		return ErrEncoderClosed
+	case e.enc != nil:
+		return e.enc.Encode(v)
+	case e.layout == nil:
+		e.values = append(e.values, v)
+		e.count++
+		return nil
+	}
+
+	elem, err := e.layout.element(v)
+	if err != nil {
+		return err
+	}
+	sep := e.layout.sep
+	if e.count == 0 {
+		sep = e.layout.open
+	}
+	e.count++
+	if _, err := e.w.WriteString(sep); err != nil {
+		return err
+	}
+	if _, err := e.w.Write(elem); err != nil {
+		return err
+	}
+	if e.unbuffered {
+		return e.w.Flush()
+	}
+	return nil
+}
+
+// Flush writes the buffered output to the underlying writer
+func (e *Encoder) Flush() error {
+	return e.w.Flush()
+}
+
+// Close completes the stream, e.g. writes the end of a JSON array, and flushes it.
+// It does not close the underlying writer. Calling Close again does nothing.
+func (e *Encoder) Close() error {
+	if e.err != nil {
+		return e.err
+	}
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+
+	switch {
+	case e.enc != nil:
+		if c, ok := e.enc.(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				return err
+			}
+		}
+	case e.layout == nil:
+		b, err := e.format.Marshal(e.values, e.opts...)
+		if err != nil {
+			return err
+		}
+		if _, err := e.w.Write(b); err != nil {
+			return err
+		}
+	case e.count == 0:
+		if _, err := e.w.WriteString(e.layout.empty); err != nil {
+			return err
+		}
+	default:
+		if _, err := e.w.WriteString(e.layout.close); err != nil {
+			return err
+		}
+	}
+	return e.w.Flush()
+}
+
+// streamLayout writes the values of a stream one at a time.
+// The values are marshaled one by one, rather than with a yaml.Encoder, so the key order sees them
+// (yaml.Encoder also ignores the errors of its writer).
+type streamLayout struct {
+	// open is written before the first value, sep between values and close after the last one
+	open, sep, close string
+	// empty is written for a stream without values
+	empty   string
+	element func(v interface{}) ([]byte, error)
+}
+
+// newDocumentsLayout returns the StreamDocuments layout of format, or nil for a registered format,
+// which is written by its encoder
+func newDocumentsLayout(format Format, opts []yaml.EncodeOption) *streamLayout {
+	switch format {
+	case FormatYAML:
+		settings := loadEncodeSettings(opts)
+		// The header and footer comments are written once, before the first value and by Close
+		opts = append(opts[:len(opts):len(opts)], withoutHeader(), withoutFooter())
+		return &streamLayout{open: settings.documentPrefix(), sep: documentStartMarker, close: settings.documentSuffix(), element: func(v interface{}) ([]byte, error) {
+			b, err := Marshal(v, opts...)
+			return bytes.TrimPrefix(b, []byte(documentStartMarker)), err
+		}}
+	case FormatJSON:
+		return jsonDocumentsLayout(opts, MarshalJSON)
+	case FormatJSONPretty:
+		return jsonDocumentsLayout(opts, func(v interface{}, opts ...yaml.EncodeOption) ([]byte, error) {
+			return MarshalJSONIndent(v, "", prettyJSONIndent, opts...)
+		})
+	case FormatJSONL:
+		return &streamLayout{element: func(v interface{}) ([]byte, error) {
+			return MarshalJSONL(v, opts...)
+		}}
+	default:
+		return nil
+	}
+}
+
+// jsonDocumentsLayout returns the StreamDocuments layout of JSON output marshaled by marshal:
+// one value after another, between the header and footer comments if they are written
+func jsonDocumentsLayout(opts []yaml.EncodeOption, marshal func(v interface{}, opts ...yaml.EncodeOption) ([]byte, error)) *streamLayout {
+	settings := loadEncodeSettings(opts)
+	opts = append(opts[:len(opts):len(opts)], withoutHeader(), withoutFooter())
+	return &streamLayout{open: settings.jsonHeader(), close: settings.jsonFooter(), element: func(v interface{}) ([]byte, error) {
+		return marshal(v, opts...)
+	}}
+}
+
+// newArrayLayout returns the StreamArray layout of format, or nil for a registered format,
+// which is marshaled as a whole
+func newArrayLayout(format Format, opts []yaml.EncodeOption) *streamLayout {
+	trimmed := func(marshal func(v interface{}) ([]byte, error)) func(v interface{}) ([]byte, error) {
+		return func(v interface{}) ([]byte, error) {
+			b, err := marshal(v)
+			return bytes.TrimRight(b, "\n"), err
+		}
+	}
+	settings := loadEncodeSettings(opts)
+	opts = append(opts[:len(opts):len(opts)], withoutHeader(), withoutFooter())
+	switch format {
+	case FormatYAML:
+		start, end := settings.documentPrefix(), settings.documentSuffix()
+		return &streamLayout{open: start, close: end, empty: start + "[]\n" + end, element: func(v interface{}) ([]byte, error) {
+			b, err := Marshal(v, opts...)
+			if err != nil {
+				return nil, err
+			}
+			return sequenceItem(bytes.TrimPrefix(b, []byte(documentStartMarker))), nil
+		}}
+	case FormatJSON:
+		header, footer := settings.jsonHeader(), settings.jsonFooter()
+		return &streamLayout{open: header + "[", sep: ", ", close: "]\n" + footer, empty: header + "[]\n" + footer, element: trimmed(func(v interface{}) ([]byte, error) {
+			return MarshalJSON(v, opts...)
+		})}
+	case FormatJSONPretty:
+		header, footer := settings.jsonHeader(), settings.jsonFooter()
+		return &streamLayout{open: header + "[\n" + prettyJSONIndent, sep: ",\n" + prettyJSONIndent, close: "\n]\n" + footer, empty: header + "[]\n" + footer, element: trimmed(func(v interface{}) ([]byte, error) {
+			return MarshalJSONIndent(v, prettyJSONIndent, prettyJSONIndent, opts...)
+		})}
+	case FormatJSONL:
+		return &streamLayout{open: "[", sep: ",", close: "]\n", empty: "[]\n", element: trimmed(func(v interface{}) ([]byte, error) {
+			return MarshalJSONL(v, opts...)
+		})}
+	default:
+		return nil
+	}
+}
+
+// sequenceItem turns a YAML document into an item of a block sequence. Only the newline that ends
+// the document is removed: the empty lines before it may belong to a block scalar kept with "|+".
+func sequenceItem(doc []byte) []byte {
+	lines := bytes.SplitAfter(bytes.TrimSuffix(doc, []byte("\n")), []byte("\n"))
+	var buf bytes.Buffer
+	for i, line := range lines {
+		switch {
+		case i == 0:
+			buf.WriteString("- ")
+		case len(bytes.TrimSpace(line)) > 0:
+			buf.WriteString("  ")
+		}
+		buf.Write(line)
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
+
+// isJSONFormat reports whether format is one of the built-in JSON formats
+func isJSONFormat(format Format) bool {
+	return format == FormatJSON || format == FormatJSONPretty || format == FormatJSONL
+}
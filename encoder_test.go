@@ -0,0 +1,256 @@
+package yamlformat
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestStreamEncoder(t *testing.T) {
+	values := []interface{}{
+		map[string]interface{}{"id": 1, "tags": []string{"a", "b"}},
+		map[string]interface{}{"id": 2, "note": "x\ny\n"},
+	}
+	tests := []struct {
+		format Format
+		mode   StreamMode
+		want   string
+		empty  string
+	}{
+		{
+			format: FormatYAML,
+			mode:   StreamDocuments,
+			want:   "id: 1\ntags:\n- a\n- b\n---\nid: 2\nnote: |\n  x\n  y\n",
+		},
+		{
+			format: FormatYAML,
+			mode:   StreamArray,
+			want:   "- id: 1\n  tags:\n  - a\n  - b\n- id: 2\n  note: |\n    x\n    y\n",
+			empty:  "[]\n",
+		},
+		{
+			format: FormatJSON,
+			mode:   StreamDocuments,
+			want:   `{"id": 1, "tags": ["a", "b"]}` + "\n" + `{"id": 2, "note": "x\ny\n"}` + "\n",
+		},
+		{
+			format: FormatJSON,
+			mode:   StreamArray,
+			want:   `[{"id": 1, "tags": ["a", "b"]}, {"id": 2, "note": "x\ny\n"}]` + "\n",
+			empty:  "[]\n",
+		},
+		{
+			format: FormatJSONPretty,
+			mode:   StreamArray,
+			want:   "[\n  {\n    \"id\": 1,\n    \"tags\": [\n      \"a\",\n      \"b\"\n    ]\n  },\n  {\n    \"id\": 2,\n    \"note\": \"x\\ny\\n\"\n  }\n]\n",
+			empty:  "[]\n",
+		},
+		{
+			format: FormatJSONL,
+			mode:   StreamDocuments,
+			want:   `{"id":1,"tags":["a","b"]}` + "\n" + `{"id":2,"note":"x\ny\n"}` + "\n",
+		},
+		{
+			format: FormatJSONL,
+			mode:   StreamArray,
+			want:   `[{"id":1,"tags":["a","b"]},{"id":2,"note":"x\ny\n"}]` + "\n",
+			empty:  "[]\n",
+		},
+	}
+
+	modeNames := map[StreamMode]string{StreamDocuments: "documents", StreamArray: "array"}
+	for _, tt := range tests {
+		t.Run(string(tt.format)+"/"+modeNames[tt.mode], func(t *testing.T) {
+			var buf bytes.Buffer
+			enc := NewStreamEncoder(&buf, tt.format, tt.mode)
+			for _, v := range values {
+				if err := enc.Encode(v); err != nil {
+					t.Fatalf("Encode failed: %v", err)
+				}
+			}
+			if err := enc.Close(); err != nil {
+				t.Fatalf("Close failed: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, buf.String()); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+			if tt.mode == StreamArray && tt.format != FormatYAML {
+				var decoded []interface{}
+				if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded) != len(values) {
+					t.Errorf("output is not a JSON array of %d values: %v", len(values), err)
+				}
+			}
+
+			buf.Reset()
+			if err := NewStreamEncoder(&buf, tt.format, tt.mode).Close(); err != nil {
+				t.Fatalf("Close failed: %v", err)
+			}
+			if buf.String() != tt.empty {
+				t.Errorf("empty stream = %q, want %q", buf.String(), tt.empty)
+			}
+		})
+	}
+}
+
+func TestStreamEncoderFlush(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewStreamEncoder(&buf, FormatJSON, StreamArray)
+	if err := enc.Encode(1); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("wrote %q before Flush, want nothing", buf.String())
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if want := "[1"; buf.String() != want {
+		t.Errorf("Flush() wrote %q, want %q", buf.String(), want)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Errorf("second Close failed: %v", err)
+	}
+	if want := "[1]\n"; buf.String() != want {
+		t.Errorf("Close() wrote %q, want %q", buf.String(), want)
+	}
+	if err := enc.Encode(2); !errors.Is(err, ErrEncoderClosed) {
+		t.Errorf("Encode() after Close error = %v, want ErrEncoderClosed", err)
+	}
+}
+
+func TestStreamEncoderDocumentStart(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewStreamEncoder(&buf, FormatYAML, StreamArray, ExplicitDocumentStart())
+	for _, v := range []int{1, 2} {
+		if err := enc.Encode(v); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if want := "---\n- 1\n- 2\n"; buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestStreamEncoderTrailingNewlines(t *testing.T) {
+	// Block scalars kept with "|+" end with empty lines that belong to the value
+	values := []interface{}{"a\n", "a\n\n", map[string]interface{}{"s": "b\n\n\n", "t": "c"}, []interface{}{"d\n\n"}}
+	for _, mode := range []StreamMode{StreamDocuments, StreamArray} {
+		var buf bytes.Buffer
+		enc := NewStreamEncoder(&buf, FormatYAML, mode)
+		for _, v := range values {
+			if err := enc.Encode(v); err != nil {
+				t.Fatalf("Encode failed: %v", err)
+			}
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+
+		var got []interface{}
+		if mode == StreamArray {
+			if err := Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Unmarshal failed: %v\n%s", err, buf.String())
+			}
+		} else {
+			var err error
+			if got, err = DecodeAll[interface{}](&buf, FormatYAML); err != nil {
+				t.Fatalf("DecodeAll failed: %v", err)
+			}
+		}
+		if diff := cmp.Diff(values, got); diff != "" {
+			t.Errorf("mode %d: decoded mismatch (-want +got):\n%s", mode, diff)
+		}
+	}
+}
+
+func TestStreamEncoderRegisteredFormat(t *testing.T) {
+	withTestRegistry(t)
+	if err := RegisterFormat(flowYAMLSpec); err != nil {
+		t.Fatalf("RegisterFormat failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	enc := NewStreamEncoder(&buf, "yaml-flow", StreamArray)
+	for _, v := range []int{1, 2} {
+		if err := enc.Encode(v); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if want := "[1, 2]\n"; buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestStreamEncoderInvalidFormat(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewStreamEncoder(&buf, "xml", StreamArray)
+	var unknown *UnknownFormatError
+	if err := enc.Encode(1); !errors.As(err, &unknown) {
+		t.Errorf("Encode() error = %v, want UnknownFormatError", err)
+	}
+	if err := enc.Close(); !errors.As(err, &unknown) {
+		t.Errorf("Close() error = %v, want UnknownFormatError", err)
+	}
+}
+
+// failingWriter fails every write
+type failingWriter struct{}
+
+var errTestWrite = errors.New("write failed")
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errTestWrite }
+
+func TestEncoderConstructors(t *testing.T) {
+	type encoder interface{ Encode(v interface{}) error }
+	tests := []struct {
+		name       string
+		newEncoder func(w io.Writer) encoder
+		want       string
+		// yaml.Encoder does not report the errors of its writer
+		writeErrors bool
+	}{
+		{name: "NewEncoder", newEncoder: func(w io.Writer) encoder { return NewEncoder(w) }, want: "a: 1\n---\na: 1\n"},
+		{name: "NewJSONEncoder", newEncoder: func(w io.Writer) encoder { return NewJSONEncoder(w) }, want: "{\"a\": 1}\n{\"a\": 1}\n"},
+		{name: "NewJSONCompactEncoder", newEncoder: func(w io.Writer) encoder { return NewJSONCompactEncoder(w) }, want: "{\"a\":1}\n{\"a\":1}\n", writeErrors: true},
+		{name: "NewJSONIndentEncoder", newEncoder: func(w io.Writer) encoder { return NewJSONIndentEncoder(w, "", " ") }, want: "{\n \"a\": 1\n}\n{\n \"a\": 1\n}\n", writeErrors: true},
+		{name: "NewJSONLEncoder", newEncoder: func(w io.Writer) encoder { return NewJSONLEncoder(w) }, want: "{\"a\":1}\n{\"a\":1}\n", writeErrors: true},
+		{name: "FormatJSONPretty", newEncoder: func(w io.Writer) encoder { return FormatJSONPretty.NewEncoder(w) }, want: "{\n  \"a\": 1\n}\n{\n  \"a\": 1\n}\n"},
+		{name: "FormatJSONL", newEncoder: func(w io.Writer) encoder { return FormatJSONL.NewEncoder(w) }, want: "{\"a\":1}\n{\"a\":1}\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			enc := tt.newEncoder(&buf)
+			for i := 0; i < 2; i++ {
+				if err := enc.Encode(map[string]int{"a": 1}); err != nil {
+					t.Fatalf("Encode failed: %v", err)
+				}
+			}
+			// Written without Flush or Close
+			if diff := cmp.Diff(tt.want, buf.String()); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+
+			if !tt.writeErrors {
+				return
+			}
+			if err := tt.newEncoder(failingWriter{}).Encode(map[string]int{"a": 1}); !errors.Is(err, errTestWrite) {
+				t.Errorf("Encode() to a failing writer error = %v, want %v", err, errTestWrite)
+			}
+		})
+	}
+}
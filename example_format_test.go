@@ -2,8 +2,11 @@ package yamlformat_test
 
 import (
 	"bytes"
+	"flag"
 	"fmt"
+	"io"
 	"log"
+	"strings"
 
 	"github.com/apstndb/go-yamlformat"
 )
@@ -26,7 +29,7 @@ func ExampleParseFormat() {
 	// JSON: json
 	// YAML: yaml
 	// json: json
-	// invalid: error - invalid format: invalid (valid: yaml, json)
+	// invalid: error - invalid format: invalid (valid: yaml, json, json-pretty, jsonl)
 }
 
 func ExampleFormat_IsValid() {
@@ -69,4 +72,46 @@ func ExampleNewEncoderForFormat_dynamicFormat() {
 	//
 	// Format json:
 	// {"code": 200, "status": "success"}
-}
\ No newline at end of file
+}
+
+// ExampleFormat_NewDecoder shows how to decode a stream of values in a given format
+func ExampleFormat_NewDecoder() {
+	input := strings.NewReader(`{"name": "foo"} {"name": "bar"}`)
+
+	decoder := yamlformat.FormatJSON.NewDecoder(input)
+	for {
+		var v map[string]interface{}
+		if err := decoder.Decode(&v); err == io.EOF {
+			break
+		} else if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(v["name"])
+	}
+
+	// YAML-only syntax is rejected in JSON format
+	var v map[string]interface{}
+	err := yamlformat.FormatJSON.Unmarshal([]byte("name: foo"), &v)
+	fmt.Println("error:", err != nil)
+
+	// Output:
+	// foo
+	// bar
+	// error: true
+}
+
+func ExampleFormatVar() {
+	fs := flag.NewFlagSet("tool", flag.ContinueOnError)
+	var format yamlformat.Format
+	yamlformat.FormatVar(fs, &format, "format", yamlformat.FormatYAML, "output format")
+
+	if err := fs.Parse([]string{"--format", "ndjson"}); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("format:", format)
+	fmt.Println("usage:", fs.Lookup("format").Usage)
+
+	// Output:
+	// format: jsonl
+	// usage: output format (one of: yaml, json, json-pretty, jsonl)
+}
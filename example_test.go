@@ -177,4 +177,29 @@ func ExampleMarshal_customOptions() {
 	// Output:
 	// nested:
 	//   key: value
-}
\ No newline at end of file
+}
+
+func ExampleMarshalJSONIndent() {
+	data := map[string]interface{}{
+		"name":  "example",
+		"items": []string{"foo", "bar"},
+		"count": 42,
+	}
+
+	jsonBytes, err := yamlformat.MarshalJSONIndent(data, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Print(string(jsonBytes))
+
+	// Output:
+	// {
+	//   "count": 42,
+	//   "items": [
+	//     "foo",
+	//     "bar"
+	//   ],
+	//   "name": "example"
+	// }
+}
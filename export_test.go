@@ -0,0 +1,4 @@
+package yamlformat
+
+// WithTestRegistry is withTestRegistry for the tests of package yamlformat_test
+var WithTestRegistry = withTestRegistry
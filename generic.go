@@ -0,0 +1,103 @@
+package yamlformat
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+
+	"github.com/goccy/go-yaml"
+)
+
+// UnmarshalAs unmarshals YAML/JSON bytes into a new value of type T, like Unmarshal
+func UnmarshalAs[T any](data []byte, opts ...yaml.DecodeOption) (T, error) {
+	var v T
+	err := Unmarshal(data, &v, opts...)
+	return v, err
+}
+
+// DecodeAll decodes every value of a stream in format into a slice:
+// each document of a YAML stream, each value of a JSON stream, or each record of JSON Lines.
+// Errors name the zero-based index of the failing document or record.
+func DecodeAll[T any](r io.Reader, format Format, opts ...yaml.DecodeOption) ([]T, error) {
+	var values []T
+	for v, err := range Records[T](r, format, opts...) {
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// Records returns an iterator over the values of a stream in format, decoded one at a time:
+// each document of a YAML stream, each value of a JSON stream, or each record of JSON Lines.
+// The iteration stops after the first error, which names the zero-based index of the failing
+// document or record, and reading stops when the loop breaks. Each value is yielded as soon as it is read,
+// so a YAML, JSON or JSON Lines stream is never read as a whole.
+//
+//	for rec, err := range yamlformat.Records[Event](r, yamlformat.FormatJSONL) {
+//		if err != nil {
+//			return err
+//		}
+//		// use rec
+//	}
+func Records[T any](r io.Reader, format Format, opts ...yaml.DecodeOption) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		if err := format.Validate(); err != nil {
+			yield(zero, err)
+			return
+		}
+		if format == FormatYAML {
+			dec := NewDocumentDecoder(r, opts...)
+			for dec.Next() {
+				var v T
+				err := dec.Decode(&v)
+				if !yield(v, err) || err != nil {
+					return
+				}
+			}
+			if err := dec.Err(); err != nil {
+				yield(zero, err)
+			}
+			return
+		}
+
+		// JSON values and JSON Lines records are read one at a time; registered formats use their decoder
+		dec := format.NewDecoder(r, opts...)
+		for i := 0; ; i++ {
+			var v T
+			err := dec.Decode(&v)
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				err = fmt.Errorf("record %d: %w", i, err)
+			}
+			if !yield(v, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// MustMarshal is like Marshal but panics if v cannot be marshaled.
+// It is intended for values known to be marshalable, such as test fixtures.
+func MustMarshal(v interface{}, opts ...yaml.EncodeOption) []byte {
+	b, err := Marshal(v, opts...)
+	if err != nil {
+		panic(fmt.Sprintf("yamlformat: Marshal(%T): %v", v, err))
+	}
+	return b
+}
+
+// MarshalTo marshals v in format f and writes it to w
+func (f Format) MarshalTo(w io.Writer, v interface{}, opts ...yaml.EncodeOption) error {
+	b, err := f.Marshal(v, opts...)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
@@ -0,0 +1,194 @@
+package yamlformat
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type genericTestRecord struct {
+	ID   int    `yaml:"id"`
+	Name string `yaml:"name"`
+}
+
+func TestUnmarshalAs(t *testing.T) {
+	got, err := UnmarshalAs[genericTestRecord]([]byte("id: 1\nname: a\n"))
+	if err != nil {
+		t.Fatalf("UnmarshalAs failed: %v", err)
+	}
+	if want := (genericTestRecord{ID: 1, Name: "a"}); got != want {
+		t.Errorf("UnmarshalAs() = %+v, want %+v", got, want)
+	}
+
+	m, err := UnmarshalAs[map[string]int]([]byte(`{"a": 1}`))
+	if err != nil {
+		t.Fatalf("UnmarshalAs failed: %v", err)
+	}
+	if diff := cmp.Diff(map[string]int{"a": 1}, m); diff != "" {
+		t.Errorf("UnmarshalAs() mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := UnmarshalAs[genericTestRecord]([]byte("id: [1]")); err == nil {
+		t.Error("UnmarshalAs() succeeded, want error")
+	}
+}
+
+func TestDecodeAll(t *testing.T) {
+	want := []genericTestRecord{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}}
+	tests := []struct {
+		format Format
+		input  string
+	}{
+		{format: FormatYAML, input: "---\nid: 1\nname: a\n...\n---\nid: 2\nname: b\n"},
+		{format: FormatJSON, input: `{"id": 1, "name": "a"} {"id": 2, "name": "b"}`},
+		{format: FormatJSONL, input: "{\"id\": 1, \"name\": \"a\"}\n{\"id\": 2, \"name\": \"b\"}\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			got, err := DecodeAll[genericTestRecord](strings.NewReader(tt.input), tt.format)
+			if err != nil {
+				t.Fatalf("DecodeAll failed: %v", err)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("DecodeAll() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDecodeAllErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		input  string
+		want   string
+	}{
+		{name: "YAML", format: FormatYAML, input: "id: 1\n---\nid: x\n", want: "document 1: "},
+		{name: "JSON", format: FormatJSON, input: `{"id": 1} {"id": `, want: "record 1: invalid JSON: "},
+		{name: "JSON Lines", format: FormatJSONL, input: "{\"id\": 1}\n{\"id\": \"x\"}\n", want: "record 1: "},
+		{name: "unknown format", format: "xml", want: "invalid format: xml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeAll[genericTestRecord](strings.NewReader(tt.input), tt.format)
+			if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("DecodeAll() error = %v, want prefix %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMustMarshal(t *testing.T) {
+	if got, want := string(MustMarshal(genericTestRecord{ID: 1, Name: "a"})), "id: 1\nname: a\n"; got != want {
+		t.Errorf("MustMarshal() = %q, want %q", got, want)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("MustMarshal() did not panic")
+		}
+	}()
+	MustMarshal(func() {})
+}
+
+func TestFormatMarshalTo(t *testing.T) {
+	var buf bytes.Buffer
+	if err := FormatJSONL.MarshalTo(&buf, genericTestRecord{ID: 1, Name: "a"}); err != nil {
+		t.Fatalf("MarshalTo failed: %v", err)
+	}
+	if want := `{"id":1,"name":"a"}` + "\n"; buf.String() != want {
+		t.Errorf("MarshalTo() wrote %q, want %q", buf.String(), want)
+	}
+}
+
+func TestRecords(t *testing.T) {
+	input := "{\"id\": 1, \"name\": \"a\"}\n{\"id\": 2, \"name\": \"b\"}\n{\"id\": \"x\"}\n"
+	var got []genericTestRecord
+	var gotErr error
+	for rec, err := range Records[genericTestRecord](strings.NewReader(input), FormatJSONL) {
+		if err != nil {
+			gotErr = err
+			continue
+		}
+		got = append(got, rec)
+	}
+	if diff := cmp.Diff([]genericTestRecord{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}}, got); diff != "" {
+		t.Errorf("Records() mismatch (-want +got):\n%s", diff)
+	}
+	if gotErr == nil || !strings.HasPrefix(gotErr.Error(), "record 2: ") {
+		t.Errorf("Records() error = %v, want an error for record 2", gotErr)
+	}
+}
+
+func TestRecordsStreaming(t *testing.T) {
+	tests := []struct {
+		format      Format
+		first, rest string
+	}{
+		{format: FormatYAML, first: "id: 1\nname: a\n---\n", rest: "id: 2\nname: b\n"},
+		{format: FormatJSON, first: `{"id": 1, "name": "a"}`, rest: ` {"id": 2, "name": "b"}`},
+		{format: FormatJSONL, first: "{\"id\": 1, \"name\": \"a\"}\n", rest: "{\"id\": 2, \"name\": \"b\"}\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			pr, pw := io.Pipe()
+			go func() {
+				_, _ = io.WriteString(pw, tt.first)
+			}()
+
+			// The rest is only written once the first record has been yielded
+			var got []genericTestRecord
+			for rec, err := range Records[genericTestRecord](pr, tt.format) {
+				if err != nil {
+					t.Fatalf("Records() error = %v", err)
+				}
+				got = append(got, rec)
+				if len(got) == 1 {
+					go func() {
+						_, _ = io.WriteString(pw, tt.rest)
+						pw.Close()
+					}()
+				}
+			}
+			want := []genericTestRecord{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("Records() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRecordsBreak(t *testing.T) {
+	// The second document is invalid, but is never read
+	input := "id: 1\n---\nid: [\n"
+	var got []genericTestRecord
+	for rec, err := range Records[genericTestRecord](strings.NewReader(input), FormatYAML) {
+		if err != nil {
+			t.Fatalf("Records() error = %v", err)
+		}
+		got = append(got, rec)
+		break
+	}
+	if diff := cmp.Diff([]genericTestRecord{{ID: 1}}, got); diff != "" {
+		t.Errorf("Records() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRecordsInvalidFormat(t *testing.T) {
+	n := 0
+	for _, err := range Records[genericTestRecord](strings.NewReader(""), "xml") {
+		n++
+		if err == nil || err.Error() != "invalid format: xml (valid: yaml, json, json-pretty, jsonl)" {
+			t.Errorf("Records() error = %v, want UnknownFormatError", err)
+		}
+	}
+	if n != 1 {
+		t.Errorf("Records() yielded %d times, want 1", n)
+	}
+}
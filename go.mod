@@ -1,7 +1,7 @@
 module github.com/apstndb/go-yamlformat
 
-go 1.22
+go 1.23
 
 require github.com/goccy/go-yaml v1.18.0
 
-require github.com/google/go-cmp v0.7.0 // indirect
+require github.com/google/go-cmp v0.7.0
@@ -0,0 +1,476 @@
+package yamlformat
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/goccy/go-yaml/token"
+)
+
+// validateJSON checks that data is exactly one valid JSON value
+func validateJSON(data []byte) error {
+	if json.Valid(data) {
+		return nil
+	}
+	// Decode again only to get a descriptive error
+	var raw json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	return errors.New("invalid JSON")
+}
+
+// jsonDecodeOptions returns opts for decoding JSON input as encoding/json does: a repeated object key
+// takes the last value, and every number decoded into interface{} is a number, such as 1e3, which
+// goccy/go-yaml reads as a string
+func jsonDecodeOptions(opts []yaml.DecodeOption) []yaml.DecodeOption {
+	allOpts := []yaml.DecodeOption{
+		yaml.AllowDuplicateMapKey(),
+		decodeSettingOption(func(p jsonProbe) { p.s.json = true }),
+	}
+	return append(allOpts, opts...)
+}
+
+// splitJSONValues splits a stream of concatenated JSON values
+func splitJSONValues(data []byte) ([][]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var values [][]byte
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err == io.EOF {
+			return values, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		values = append(values, raw)
+	}
+}
+
+// Decoder decodes a stream of JSON values or JSON Lines records, one per call to Decode.
+// Unlike yaml.Decoder, it reads only as much input as the next value needs,
+// so each value is decoded as soon as it is written, e.g. to a pipe.
+type Decoder struct {
+	// next returns the next value, or io.EOF after the last one
+	next func() ([]byte, error)
+	opts []yaml.DecodeOption
+}
+
+// Decode decodes the next value into v. It returns io.EOF after the last value.
+func (d *Decoder) Decode(v interface{}) error {
+	data, err := d.next()
+	if err != nil {
+		return err
+	}
+	return Unmarshal(data, v, d.opts...)
+}
+
+// nextJSONValueFunc returns a function that reads the next value of a stream of JSON values
+func nextJSONValueFunc(r io.Reader) func() ([]byte, error) {
+	dec := json.NewDecoder(r)
+	return func() ([]byte, error) {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		return raw, nil
+	}
+}
+
+// nextJSONLineFunc returns a function that reads the next record of JSON Lines, skipping blank lines
+func nextJSONLineFunc(r io.Reader) func() ([]byte, error) {
+	br := bufio.NewReader(r)
+	var line int
+	return func() ([]byte, error) {
+		for {
+			b, err := br.ReadBytes('\n')
+			if len(b) > 0 {
+				line++
+			}
+			if trimmed := bytes.TrimSpace(b); len(trimmed) > 0 {
+				if err := validateJSON(trimmed); err != nil {
+					return nil, fmt.Errorf("line %d: %w", line, err)
+				}
+				return trimmed, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// splitJSONLines splits JSON Lines input into records, skipping blank lines
+func splitJSONLines(data []byte) ([][]byte, error) {
+	var records [][]byte
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if err := validateJSON(line); err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		records = append(records, line)
+	}
+	return records, nil
+}
+
+// compactJSONLine compacts a JSON value into a single line terminated by a newline
+func compactJSONLine(data []byte) ([]byte, error) {
+	b, err := reformatJSON(data, "", "")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// indentJSON reformats a JSON value with one element per line, terminated by a newline
+func indentJSON(data []byte, prefix, indent string) ([]byte, error) {
+	b, err := reformatJSON(data, prefix, indent)
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// reformatJSON rewrites JSON produced by yaml.Encoder in the layout and escaping of encoding/json.
+// An empty indent produces compact output like json.Marshal,
+// otherwise the output is indented like json.MarshalIndent.
+// Numbers are copied verbatim, so the package's number formatting is kept.
+func reformatJSON(data []byte, prefix, indent string) ([]byte, error) {
+	jw := &jsonNodeWriter{prefix: prefix, indent: indent}
+	return jw.format(data)
+}
+
+// jsonNodeWriter writes YAML AST nodes as JSON
+type jsonNodeWriter struct {
+	buf    bytes.Buffer
+	prefix string
+	indent string
+	// spaced separates the keys and elements of single-line output with ": " and ", " like yaml.Encoder
+	spaced bool
+	// comments are written as JSONC comments if not nil, by goccy/go-yaml path
+	comments yaml.CommentMap
+	// pending are the line and foot comments of the last value, written before the next newline
+	pending []pendingComment
+	// raw writes the strings that start with rawJSONMarker as the JSON text after it
+	raw bool
+}
+
+// pendingComment is a comment to write after a value
+type pendingComment struct {
+	text  string
+	foot  bool
+	depth int
+}
+
+// format writes data, a document encoded by yaml.Encoder
+func (jw *jsonNodeWriter) format(data []byte) ([]byte, error) {
+	// yaml.Encoder escapes some characters in YAML syntax (e.g. "\x00") even in JSON mode,
+	// so the output is parsed as YAML rather than JSON.
+	file, err := parser.ParseBytes(data, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(file.Docs) != 1 {
+		return nil, fmt.Errorf("want exactly one JSON value, got %d", len(file.Docs))
+	}
+	jw.headComments("$", 0)
+	if err := jw.write(file.Docs[0].Body, 0, "$"); err != nil {
+		return nil, err
+	}
+	jw.tailComments("$", 0)
+	jw.flushComments()
+	return jw.buf.Bytes(), nil
+}
+
+func (jw *jsonNodeWriter) newline(depth int) {
+	jw.flushComments()
+	if jw.indent == "" {
+		return
+	}
+	jw.buf.WriteByte('\n')
+	jw.buf.WriteString(jw.prefix)
+	for i := 0; i < depth; i++ {
+		jw.buf.WriteString(jw.indent)
+	}
+}
+
+// separator writes the separator between a key and its value, or between elements if sep is ','
+func (jw *jsonNodeWriter) separator(sep byte) {
+	jw.buf.WriteByte(sep)
+	if jw.spaced && jw.indent == "" || sep == ':' && jw.indent != "" {
+		jw.buf.WriteByte(' ')
+	}
+}
+
+// childPath returns the path of a mapping value or sequence element, if comments are written
+func (jw *jsonNodeWriter) childPath(path string, key interface{}) string {
+	if jw.comments == nil {
+		return ""
+	}
+	if index, ok := key.(int); ok {
+		return fmt.Sprintf("%s[%d]", path, index)
+	}
+	// A key that goccy/go-yaml paths cannot address gets no comments
+	childPath, _ := childCommentPath(path, fmt.Sprint(key))
+	return childPath
+}
+
+// comment writes a comment: a "//" line in indented output, otherwise a "/* */" block
+func (jw *jsonNodeWriter) comment(text string) {
+	if jw.indent != "" {
+		jw.buf.WriteString("//" + text)
+		return
+	}
+	jw.buf.WriteString("/*" + strings.ReplaceAll(text, "*/", "* /") + " */")
+}
+
+// headComments writes the head comments of the value at path, before the value at depth
+func (jw *jsonNodeWriter) headComments(path string, depth int) {
+	for _, c := range jw.comments[path] {
+		if c.Position != yaml.CommentHeadPosition {
+			continue
+		}
+		for _, text := range c.Texts {
+			jw.comment(text)
+			if jw.indent == "" {
+				jw.buf.WriteByte(' ')
+			} else if path == "$" {
+				jw.buf.WriteByte('\n')
+			} else {
+				jw.newline(depth)
+			}
+		}
+	}
+}
+
+// tailComments queues the line and foot comments of the value at path, written before the next newline
+func (jw *jsonNodeWriter) tailComments(path string, depth int) {
+	for _, c := range jw.comments[path] {
+		for _, text := range c.Texts {
+			switch c.Position {
+			case yaml.CommentLinePosition:
+				jw.pending = append(jw.pending, pendingComment{text: text, depth: depth})
+			case yaml.CommentFootPosition:
+				jw.pending = append(jw.pending, pendingComment{text: text, foot: true, depth: depth})
+			}
+		}
+	}
+	if jw.indent == "" {
+		// Before the "," of the next key or element
+		jw.flushComments()
+	}
+}
+
+// flushComments writes the pending line and foot comments
+func (jw *jsonNodeWriter) flushComments() {
+	pending := jw.pending
+	jw.pending = nil
+	for _, c := range pending {
+		if c.foot && jw.indent != "" {
+			jw.newline(c.depth)
+		} else {
+			jw.buf.WriteByte(' ')
+		}
+		jw.comment(c.text)
+	}
+}
+
+func (jw *jsonNodeWriter) writeString(s string) error {
+	if text, ok := strings.CutPrefix(s, rawJSONMarker); ok && jw.raw {
+		jw.buf.WriteString(text)
+		return nil
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	jw.buf.Write(b)
+	return nil
+}
+
+func (jw *jsonNodeWriter) write(node ast.Node, depth int, path string) error {
+	switch n := node.(type) {
+	case nil, *ast.NullNode:
+		jw.buf.WriteString("null")
+	case *ast.BoolNode:
+		jw.buf.WriteString(strconv.FormatBool(n.Value))
+	case *ast.IntegerNode:
+		jw.buf.WriteString(n.Token.Value)
+	case *ast.FloatNode:
+		jw.buf.WriteString(n.Token.Value)
+	case *ast.StringNode:
+		if n.Token.Type != token.DoubleQuoteType && n.Token.Type != token.SingleQuoteType {
+			// Plain scalars in JSON output are numbers too large for int64/uint64
+			jw.buf.WriteString(n.Token.Value)
+			return nil
+		}
+		return jw.writeString(n.Value)
+	case *ast.LiteralNode:
+		return jw.writeString(n.Value.Value)
+	case *ast.InfinityNode, *ast.NanNode:
+		return fmt.Errorf("json: unsupported value: %s", n.String())
+	case *ast.TagNode:
+		return jw.write(n.Value, depth, path)
+	case *ast.AnchorNode:
+		return jw.write(n.Value, depth, path)
+	case *ast.MappingKeyNode:
+		return jw.write(n.Value, depth, path)
+	case *ast.MappingValueNode:
+		return jw.writeMapping([]*ast.MappingValueNode{n}, depth, path)
+	case *ast.MappingNode:
+		return jw.writeMapping(n.Values, depth, path)
+	case *ast.SequenceNode:
+		if len(n.Values) == 0 {
+			jw.buf.WriteString("[]")
+			return nil
+		}
+		jw.buf.WriteByte('[')
+		for i, v := range n.Values {
+			if i > 0 {
+				jw.separator(',')
+			}
+			jw.newline(depth + 1)
+			elemPath := jw.childPath(path, i)
+			jw.headComments(elemPath, depth+1)
+			if err := jw.write(v, depth+1, elemPath); err != nil {
+				return err
+			}
+			jw.tailComments(elemPath, depth+1)
+		}
+		jw.newline(depth)
+		jw.buf.WriteByte(']')
+	default:
+		return fmt.Errorf("json: unsupported node: %s", node.Type())
+	}
+	return nil
+}
+
+func (jw *jsonNodeWriter) writeMapping(values []*ast.MappingValueNode, depth int, path string) error {
+	if len(values) == 0 {
+		jw.buf.WriteString("{}")
+		return nil
+	}
+	jw.buf.WriteByte('{')
+	for i, mv := range values {
+		if i > 0 {
+			jw.separator(',')
+		}
+		jw.newline(depth + 1)
+		key := mappingKey(mv.Key)
+		valuePath := jw.childPath(path, key)
+		jw.headComments(valuePath, depth+1)
+		if err := jw.writeString(key); err != nil {
+			return err
+		}
+		jw.separator(':')
+		if err := jw.write(mv.Value, depth+1, valuePath); err != nil {
+			return err
+		}
+		jw.tailComments(valuePath, depth+1)
+	}
+	jw.newline(depth)
+	jw.buf.WriteByte('}')
+	return nil
+}
+
+// mappingKey returns the string value of a mapping key node
+func mappingKey(key ast.MapKeyNode) string {
+	switch k := key.(type) {
+	case *ast.StringNode:
+		return k.Value
+	case *ast.MappingKeyNode:
+		if s, ok := k.Value.(*ast.StringNode); ok {
+			return s.Value
+		}
+	}
+	return key.GetToken().Value
+}
+
+// documentWriter rewrites the documents written by yaml.Encoder.
+// yaml.Encoder writes each document with a single Write call, and writes
+// a separate "---\n" document separator before every document but the first,
+// which is dropped if dropSeparators is set (it is not valid JSON).
+type documentWriter struct {
+	w              io.Writer
+	rewrite        func(doc []byte) ([]byte, error)
+	dropSeparators bool
+}
+
+func (dw *documentWriter) Write(p []byte) (int, error) {
+	if string(p) == "---\n" {
+		if dw.dropSeparators {
+			return len(p), nil
+		}
+		return dw.w.Write(p)
+	}
+	doc, err := dw.rewrite(p)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := dw.w.Write(doc); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// jsonLayout is the layout of MarshalJSON and NewJSONEncoder output.
+// The zero value keeps the single-line output of yaml.Encoder.
+type jsonLayout struct {
+	set    bool
+	prefix string
+	indent string
+}
+
+// jsonIndent sets the layout of MarshalJSON and NewJSONEncoder output:
+// compact like MarshalJSONCompact if indent is empty, otherwise indented like MarshalJSONIndent.
+// Both are terminated by a newline.
+func jsonIndent(prefix, indent string) yaml.EncodeOption {
+	return settingOption(func(p jsonLayoutProbe) { p.s.jsonLayout = jsonLayout{set: true, prefix: prefix, indent: indent} })
+}
+
+// rewriter returns a function that reformats a JSON document in the layout, or nil if the layout is not set
+func (l jsonLayout) rewriter() func(doc []byte) ([]byte, error) {
+	switch {
+	case !l.set:
+		return nil
+	case l.indent == "":
+		return compactJSONLine
+	default:
+		return func(doc []byte) ([]byte, error) {
+			return indentJSON(doc, l.prefix, l.indent)
+		}
+	}
+}
+
+// jsonRewriter returns the function that lays out the JSON documents of c in l, or nil to keep the output
+// of yaml.Encoder. With JSONC, it writes the comments of the settings and of the struct tags in v,
+// which is invalid if unknown.
+func (c encodeConfig) jsonRewriter(l jsonLayout, v reflect.Value) func(doc []byte) ([]byte, error) {
+	if !c.settings.jsonc {
+		return l.rewriter()
+	}
+	comments := c.settings.commentMap(v)
+	return func(doc []byte) ([]byte, error) {
+		jw := &jsonNodeWriter{prefix: l.prefix, indent: l.indent, spaced: !l.set, comments: comments}
+		b, err := jw.format(doc)
+		if err != nil {
+			return nil, err
+		}
+		return append(b, '\n'), nil
+	}
+}
@@ -0,0 +1,176 @@
+package yamlformat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+// jsonTestPoint writes itself as a JSON object with MarshalJSON
+type jsonTestPoint struct{ X, Y int }
+
+func (p jsonTestPoint) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf(`{ "y": %d, "x": %d }`, p.Y, p.X)), nil
+}
+
+// jsonTestLevel writes itself as text, from a pointer receiver
+type jsonTestLevel int
+
+func (l *jsonTestLevel) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("level-%d <%d>", *l, *l)), nil
+}
+
+func TestMarshalJSONCompact(t *testing.T) {
+	type item struct {
+		Name  string   `json:"name"`
+		Tags  []string `json:"tags"`
+		Price float64  `json:"price"`
+	}
+
+	type base struct {
+		ID   int
+		Name string
+	}
+	type other struct {
+		Name string
+		Note string `json:"note,omitempty"`
+	}
+	type embedded struct {
+		base
+		*other
+		Kind string `json:"kind"`
+	}
+	type conflict struct {
+		base
+		other
+	}
+	type options struct {
+		Count    int           `json:",string"`
+		Text     string        `json:"text,string"`
+		Ok       *bool         `json:"ok,string"`
+		Empty    []int         `json:",omitempty"`
+		Zero     time.Time     `json:",omitzero"`
+		Skipped  string        `json:"-"`
+		Duration time.Duration `json:"duration"`
+	}
+	yes := true
+
+	// Values whose encoding/json output the package does not change
+	compatible := []struct {
+		name  string
+		input interface{}
+	}{
+		{name: "map", input: map[string]interface{}{"b": 1, "a": []interface{}{true, nil, "x"}}},
+		{name: "struct", input: item{Name: "foo", Tags: []string{"a", "b"}, Price: 9.99}},
+		{name: "nested empty", input: map[string]interface{}{"m": map[string]interface{}{}, "s": []interface{}{}}},
+		{name: "HTML characters", input: "<a href=\"x\">&amp;</a>"},
+		{name: "control characters", input: "\x00\x01\x1f\b\f\n\r\t\x7f"},
+		{name: "line separators", input: "  "},
+		{name: "unicode", input: "日本語 😀 é"},
+		{name: "integer keys", input: map[int]string{10: "a", 9: "b"}},
+		{name: "large integers", input: []interface{}{int64(math.MaxInt64), int64(math.MinInt64), uint64(math.MaxUint64)}},
+		{name: "floats", input: []float64{0, 0.1, -2.5, 3.141592653589793, 1e20, 0.000001}},
+		{name: "whole float", input: 100.0},
+		{name: "untagged struct", input: struct {
+			Name    string
+			Count   int
+			private int
+		}{Name: "x", Count: 2, private: 3}},
+		{name: "embedded struct", input: embedded{base: base{ID: 1, Name: "b"}, Kind: "k"}},
+		{name: "embedded pointer", input: embedded{base: base{ID: 1, Name: "b"}, other: &other{Name: "o", Note: "n"}, Kind: "k"}},
+		{name: "conflicting embedded fields", input: conflict{base: base{ID: 1, Name: "b"}, other: other{Name: "o"}}},
+		{name: "bytes", input: []byte("hello, world")},
+		{name: "bytes field", input: struct{ Data, Nil []byte }{Data: []byte{0, 1, 2, 250}}},
+		{name: "field options", input: options{Count: 3, Text: "a\"b", Ok: &yes, Skipped: "s", Duration: 1500 * time.Millisecond}},
+		{name: "raw message", input: struct{ R json.RawMessage }{R: json.RawMessage(`{"z": 1, "a": [2, "<"]}`)}},
+		{name: "nested marshaler", input: map[string]interface{}{"p": jsonTestPoint{X: 1, Y: 2}, "ps": []jsonTestPoint{{X: 3}}}},
+		{name: "nil marshaler", input: struct{ P *jsonTestPoint }{}},
+		{name: "text marshaler", input: &struct{ L jsonTestLevel }{L: 3}},
+		{name: "unaddressable text marshaler", input: struct{ L jsonTestLevel }{L: 3}},
+		{name: "time", input: time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)},
+		{name: "invalid UTF-8", input: map[string]interface{}{"a\xffb": "a\xffb", "s": []string{"\xc3", "é\x80"}}},
+	}
+
+	for _, tt := range compatible {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MarshalJSONCompact(tt.input)
+			if err != nil {
+				t.Fatalf("MarshalJSONCompact failed: %v", err)
+			}
+			want, err := json.Marshal(tt.input)
+			if err != nil {
+				t.Fatalf("json.Marshal failed: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("MarshalJSONCompact() = %q, want %q", got, want)
+			}
+		})
+	}
+
+	// Values where the package's number rules intentionally differ from encoding/json
+	numbers := []struct {
+		name  string
+		input interface{}
+		want  string
+	}{
+		{name: "no scientific notation for large floats", input: 1e21, want: "1000000000000000000000"},
+		{name: "no scientific notation for small floats", input: 1e-7, want: "0.0000001"},
+	}
+
+	for _, tt := range numbers {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MarshalJSONCompact(tt.input)
+			if err != nil {
+				t.Fatalf("MarshalJSONCompact failed: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("MarshalJSONCompact() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewJSONCompactEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	encoder := NewJSONCompactEncoder(&buf)
+
+	var want bytes.Buffer
+	jsonEncoder := json.NewEncoder(&want)
+
+	for _, v := range []interface{}{map[string]interface{}{"a": "<b>"}, []int{1, 2}, "x"} {
+		if err := encoder.Encode(v); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+		if err := jsonEncoder.Encode(v); err != nil {
+			t.Fatalf("json Encode failed: %v", err)
+		}
+	}
+
+	if buf.String() != want.String() {
+		t.Errorf("Encode() = %q, want %q", buf.String(), want.String())
+	}
+}
+
+func TestMarshalJSONIndentMatchesEncodingJSON(t *testing.T) {
+	input := map[string]interface{}{
+		"name":  "<example>",
+		"items": []interface{}{"a", map[string]interface{}{"k": []interface{}{}}},
+		"empty": map[string]interface{}{},
+	}
+
+	got, err := MarshalJSONIndent(input, "#", "\t")
+	if err != nil {
+		t.Fatalf("MarshalJSONIndent failed: %v", err)
+	}
+	want, err := json.MarshalIndent(input, "#", "\t")
+	if err != nil {
+		t.Fatalf("json.MarshalIndent failed: %v", err)
+	}
+	want = append(want, '\n')
+	if !bytes.Equal(got, want) {
+		t.Errorf("MarshalJSONIndent() = %q, want %q", got, want)
+	}
+}
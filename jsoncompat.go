@@ -0,0 +1,478 @@
+package yamlformat
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/goccy/go-yaml"
+)
+
+// jsonObject is a JSON object converted from a struct or map by jsonCompatible, with its members in output order.
+// goccy/go-yaml encodes it through MarshalYAML; the key order tells struct fields from map keys with isStruct.
+type jsonObject struct {
+	members  yaml.MapSlice
+	isStruct bool
+}
+
+// MarshalYAML implements yaml.InterfaceMarshaler
+func (o jsonObject) MarshalYAML() (interface{}, error) {
+	return o.members, nil
+}
+
+var jsonObjectType = reflect.TypeOf(jsonObject{})
+
+// jsonCompatible converts v into the structure that encoding/json.Marshal writes:
+// structs become objects with the fields and names of encoding/json (json tags, Go names, promoted fields of
+// embedded structs, omitempty, omitzero and the string option), maps become objects sorted by key,
+// and []byte becomes a base64 string. Values with a MarshalJSON or MarshalText method, and strings with
+// invalid UTF-8, become the JSON text of encoding/json (see rawJSON). Other values, including those with
+// only YAML marshaler methods, are kept, so the package's number formatting and marshalers apply to them.
+func (s encodeSettings) jsonCompatible(v interface{}) (interface{}, error) {
+	return s.jsonCompatibleValue(reflect.ValueOf(v))
+}
+
+func (s encodeSettings) jsonCompatibleValue(rv reflect.Value) (interface{}, error) {
+	if !rv.IsValid() {
+		return nil, nil
+	}
+	if rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return s.jsonCompatibleValue(rv.Elem())
+	}
+	if hasMarshaler(rv) {
+		// The result is stored in interface{} values, where goccy/go-yaml writes big numbers as strings
+		if !containsDefault(s.without, DefaultBigNumbers) {
+			if b, ok, err := marshalBigNumber(leafInterface(rv)); ok || err != nil {
+				return numberLiteral(b), err
+			}
+		}
+		if v, ok, err := jsonMethodValue(rv); ok || err != nil {
+			return v, err
+		}
+		if !hasJSONMethod(rv.Type()) {
+			return leafInterface(rv), nil
+		}
+		// Only a pointer to rv has the method, which encoding/json does not call as rv is not addressable
+	}
+	switch rv.Kind() {
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return s.jsonCompatibleValue(rv.Elem())
+	case reflect.Struct:
+		return s.jsonStruct(rv)
+	case reflect.Map:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return s.jsonMap(rv)
+	case reflect.Slice:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		if isByteSlice(rv.Type()) {
+			return base64.StdEncoding.EncodeToString(rv.Bytes()), nil
+		}
+		fallthrough
+	case reflect.Array:
+		elems := make([]interface{}, rv.Len())
+		for i := range elems {
+			elem, err := s.jsonCompatibleValue(rv.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			elems[i] = elem
+		}
+		return elems, nil
+	}
+	if rv.Type() == durationType {
+		// encoding/json writes time.Duration as an integer, goccy/go-yaml as a string like "1s"
+		return rv.Int(), nil
+	}
+	if rv.Kind() == reflect.String {
+		if str := rv.String(); !utf8.ValidString(str) {
+			return jsonString(str), nil
+		}
+	}
+	return leafInterface(rv), nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// rawJSONMarker starts the strings of jsonCompatible that stand for JSON text written as is by MarshalJSONCompact.
+// goccy/go-yaml would convert the text to YAML, which is not valid in its JSON output, so it is carried in a string.
+// The random part keeps the marker out of the strings of the data.
+var rawJSONMarker = "yamlformat-raw-" + strconv.FormatUint(rand.Uint64(), 36) + ":"
+
+// rawJSON returns the string that stands for the JSON text b
+func rawJSON(b []byte) string {
+	return rawJSONMarker + string(b)
+}
+
+// jsonString returns s, or if it has invalid UTF-8, the JSON string of encoding/json
+// with each invalid byte replaced by U+FFFD
+func jsonString(s string) string {
+	if utf8.ValidString(s) {
+		return s
+	}
+	b, _ := json.Marshal(s)
+	return rawJSON(b)
+}
+
+// jsonMethodValue returns the value that encoding/json writes for rv with its MarshalJSON or MarshalText method,
+// or false if encoding/json calls neither. Like encoding/json, the methods of a pointer to rv are called
+// if rv is addressable, MarshalJSON is preferred to MarshalText and a nil pointer is written as null.
+func jsonMethodValue(rv reflect.Value) (interface{}, bool, error) {
+	candidates := []reflect.Value{rv}
+	if rv.Kind() != reflect.Pointer && rv.CanAddr() {
+		candidates = []reflect.Value{rv.Addr(), rv}
+	}
+	for _, c := range candidates {
+		if !c.CanInterface() || !c.Type().Implements(jsonMarshalerType) {
+			continue
+		}
+		if c.Kind() == reflect.Pointer && c.IsNil() {
+			return nil, true, nil
+		}
+		b, err := c.Interface().(json.Marshaler).MarshalJSON()
+		if err != nil {
+			return nil, true, fmt.Errorf("json: error calling MarshalJSON for type %s: %w", c.Type(), err)
+		}
+		var compact, escaped bytes.Buffer
+		if err := json.Compact(&compact, b); err != nil {
+			return nil, true, fmt.Errorf("json: error calling MarshalJSON for type %s: %w", c.Type(), err)
+		}
+		json.HTMLEscape(&escaped, compact.Bytes())
+		return rawJSON(escaped.Bytes()), true, nil
+	}
+	for _, c := range candidates {
+		if !c.CanInterface() || !c.Type().Implements(textMarshalerType) {
+			continue
+		}
+		if c.Kind() == reflect.Pointer && c.IsNil() {
+			return nil, true, nil
+		}
+		b, err := c.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return nil, true, fmt.Errorf("json: error calling MarshalText for type %s: %w", c.Type(), err)
+		}
+		return jsonString(string(b)), true, nil
+	}
+	return nil, false, nil
+}
+
+// hasJSONMethod reports whether values of type t, or pointers to them, have a MarshalJSON or MarshalText method
+func hasJSONMethod(t reflect.Type) bool {
+	if t.Kind() != reflect.Pointer {
+		t = reflect.PointerTo(t)
+	}
+	return t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType)
+}
+
+// hasMarshaler reports whether rv, or a pointer to it, has a marshaler method.
+// Such values are kept for the marshalers of the package (e.g. for big.Int) and goccy/go-yaml.
+func hasMarshaler(rv reflect.Value) bool {
+	if !rv.CanInterface() {
+		return false
+	}
+	return isMarshaler(rv.Interface()) || rv.Kind() != reflect.Pointer && isMarshaler(reflect.New(rv.Type()).Interface())
+}
+
+// isByteSlice reports whether encoding/json writes values of the slice type t as base64 strings
+func isByteSlice(t reflect.Type) bool {
+	if t.Elem().Kind() != reflect.Uint8 {
+		return false
+	}
+	p := reflect.PointerTo(t.Elem())
+	return !p.Implements(jsonMarshalerType) && !p.Implements(textMarshalerType)
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// leafInterface returns the value of rv, which is a scalar or has a marshaler. Scalar fields reached through
+// unexported embedded structs cannot be read with Interface, so their values are copied.
+func leafInterface(rv reflect.Value) interface{} {
+	if rv.CanInterface() {
+		return rv.Interface()
+	}
+	c := reflect.New(rv.Type()).Elem()
+	switch rv.Kind() {
+	case reflect.Bool:
+		c.SetBool(rv.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		c.SetInt(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		c.SetUint(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		c.SetFloat(rv.Float())
+	case reflect.String:
+		c.SetString(rv.String())
+	default:
+		return nil
+	}
+	return c.Interface()
+}
+
+func (s encodeSettings) jsonStruct(rv reflect.Value) (interface{}, error) {
+	members := yaml.MapSlice{}
+	for _, f := range jsonStructFields(rv.Type()) {
+		fv, ok := fieldByIndex(rv, f.index)
+		if !ok || f.omitEmpty && isEmptyJSONValue(fv) || f.omitZero && isZeroJSONValue(fv) {
+			continue
+		}
+		var value interface{}
+		var err error
+		if f.quoted {
+			value, err = s.quotedJSONValue(fv)
+		} else {
+			value, err = s.jsonCompatibleValue(fv)
+		}
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, yaml.MapItem{Key: f.name, Value: value})
+	}
+	return jsonObject{members: members, isStruct: true}, nil
+}
+
+// fieldByIndex returns the field of rv at index, or false if it is in a nil embedded pointer
+func fieldByIndex(rv reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && rv.Kind() == reflect.Pointer {
+			if rv.IsNil() {
+				return reflect.Value{}, false
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(x)
+	}
+	return rv, true
+}
+
+// quotedJSONValue returns the value of a field with the string option: scalars are written inside a JSON string
+func (s encodeSettings) quotedJSONValue(rv reflect.Value) (interface{}, error) {
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.String:
+		b, err := json.Marshal(rv.String())
+		return string(b), err
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32:
+		return s.floatFormat.Format(rv.Float(), 32), nil
+	case reflect.Float64:
+		return s.floatFormat.Format(rv.Float(), 64), nil
+	}
+	return s.jsonCompatibleValue(rv)
+}
+
+func (s encodeSettings) jsonMap(rv reflect.Value) (interface{}, error) {
+	members := make(yaml.MapSlice, 0, rv.Len())
+	for iter := rv.MapRange(); iter.Next(); {
+		key, err := jsonMapKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		value, err := s.jsonCompatibleValue(iter.Value())
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, yaml.MapItem{Key: key, Value: value})
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].Key.(string) < members[j].Key.(string) })
+	for i := range members {
+		members[i].Key = jsonString(members[i].Key.(string))
+	}
+	return jsonObject{members: members}, nil
+}
+
+// jsonMapKey returns the object key of a map key like encoding/json
+func jsonMapKey(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+	if tm, ok := k.Interface().(encoding.TextMarshaler); ok {
+		if k.Kind() == reflect.Pointer && k.IsNil() {
+			return "", nil
+		}
+		b, err := tm.MarshalText()
+		return string(b), err
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("json: unsupported map key type: %s", k.Type())
+}
+
+// isEmptyJSONValue reports whether omitempty omits rv
+func isEmptyJSONValue(rv reflect.Value) bool {
+	switch rv.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return rv.Len() == 0
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Interface, reflect.Pointer:
+		return rv.IsZero()
+	}
+	return false
+}
+
+// isZeroJSONValue reports whether omitzero omits rv: it is the zero value, or its IsZero method returns true
+func isZeroJSONValue(rv reflect.Value) bool {
+	if rv.CanInterface() {
+		if z, ok := rv.Interface().(interface{ IsZero() bool }); ok {
+			if rv.Kind() == reflect.Pointer && rv.IsNil() {
+				return true
+			}
+			return z.IsZero()
+		}
+	}
+	return rv.IsZero()
+}
+
+// jsonField is a field of a struct as written by encoding/json
+type jsonField struct {
+	name      string
+	index     []int
+	tagged    bool
+	omitEmpty bool
+	omitZero  bool
+	quoted    bool
+}
+
+var jsonFieldCache sync.Map // reflect.Type → []jsonField
+
+// jsonStructFields returns the fields that encoding/json writes for the struct type t, in output order.
+// Fields of embedded structs without a json name are promoted; of the fields with the same name,
+// the least nested one wins, then the only tagged one, and the others are dropped.
+func jsonStructFields(t reflect.Type) []jsonField {
+	if fields, ok := jsonFieldCache.Load(t); ok {
+		return fields.([]jsonField)
+	}
+	var all []jsonField
+	collectJSONFields(t, nil, map[reflect.Type]bool{}, &all)
+
+	byName := map[string][]jsonField{}
+	for _, f := range all {
+		byName[f.name] = append(byName[f.name], f)
+	}
+	var fields []jsonField
+	for _, f := range all {
+		if dominant, ok := dominantJSONField(byName[f.name]); ok && slices.Equal(dominant.index, f.index) {
+			fields = append(fields, f)
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool { return slices.Compare(fields[i].index, fields[j].index) < 0 })
+	jsonFieldCache.Store(t, fields)
+	return fields
+}
+
+// collectJSONFields adds the fields of the struct type t, whose own index is index, to fields
+func collectJSONFields(t reflect.Type, index []int, visiting map[reflect.Type]bool, fields *[]jsonField) {
+	if visiting[t] {
+		return
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		ft := sf.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous {
+			if !sf.IsExported() && ft.Kind() != reflect.Struct {
+				continue
+			}
+		} else if !sf.IsExported() {
+			continue
+		}
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		fieldIndex := append(index[:len(index):len(index)], i)
+		if name == "" && sf.Anonymous && ft.Kind() == reflect.Struct {
+			collectJSONFields(ft, fieldIndex, visiting, fields)
+			continue
+		}
+		f := jsonField{name: name, index: fieldIndex, tagged: name != ""}
+		if name == "" {
+			f.name = sf.Name
+		}
+		for _, opt := range strings.Split(options, ",") {
+			switch opt {
+			case "omitempty":
+				f.omitEmpty = true
+			case "omitzero":
+				f.omitZero = true
+			case "string":
+				switch ft.Kind() {
+				case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+					reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+					reflect.Float32, reflect.Float64, reflect.String:
+					f.quoted = true
+				}
+			}
+		}
+		*fields = append(*fields, f)
+	}
+}
+
+// dominantJSONField returns the field that wins among fields with the same name
+func dominantJSONField(fields []jsonField) (jsonField, bool) {
+	depth := len(fields[0].index)
+	for _, f := range fields[1:] {
+		depth = min(depth, len(f.index))
+	}
+	var candidates []jsonField
+	for _, f := range fields {
+		if len(f.index) == depth {
+			candidates = append(candidates, f)
+		}
+	}
+	if len(candidates) == 1 {
+		return candidates[0], true
+	}
+	var tagged []jsonField
+	for _, f := range candidates {
+		if f.tagged {
+			tagged = append(tagged, f)
+		}
+	}
+	if len(tagged) == 1 {
+		return tagged[0], true
+	}
+	return jsonField{}, false
+}
@@ -0,0 +1,395 @@
+package yamlformat
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// jsonSource is the source of a JSON document being edited, with the offsets of its values
+type jsonSource struct {
+	data []byte
+	root *jsonNode
+	// spaced is set if single-line objects and arrays separate with ": " and ", "
+	spaced       bool
+	spacingFound bool
+	// indent is one level of indentation of the lines of multi-line objects and arrays
+	indent string
+	// multiline is set if the document is written on several lines, or is FormatJSONPretty
+	multiline bool
+	edits     []jsonEdit
+}
+
+// jsonNode is a JSON value in the source
+type jsonNode struct {
+	start, end int
+	// kind is '{' for an object, '[' for an array and 0 for a scalar
+	kind    byte
+	members []jsonMember
+}
+
+// jsonMember is a member of an object, or an element of an array
+type jsonMember struct {
+	key              string
+	keyStart, keyEnd int // the value offsets for an element
+	value            *jsonNode
+}
+
+// jsonEdit replaces the source between start and end with text
+type jsonEdit struct {
+	start, end int
+	text       string
+}
+
+// parseJSONSource indexes the JSON document of d
+func (d *Document) parseJSONSource() (*jsonSource, error) {
+	s := &jsonSource{data: d.src, spaced: true, multiline: d.format == FormatJSONPretty}
+	root, end, err := s.parseValue(s.skipSpace(0))
+	if err != nil {
+		return nil, err
+	}
+	if s.skipSpace(end) != len(s.data) {
+		return nil, fmt.Errorf("invalid JSON: unexpected %q after the value", s.data[s.skipSpace(end)])
+	}
+	s.root = root
+	if s.spans(root) {
+		s.multiline = true
+	}
+	if s.indent == "" {
+		s.indent = prettyJSONIndent
+	}
+	return s, nil
+}
+
+// skipSpace returns the offset of the first byte from i that is not whitespace
+func (s *jsonSource) skipSpace(i int) int {
+	for i < len(s.data) && strings.IndexByte(" \t\r\n", s.data[i]) >= 0 {
+		i++
+	}
+	return i
+}
+
+// expect returns an error unless the byte at i is one of chars
+func (s *jsonSource) expect(i int, chars string) error {
+	if i >= len(s.data) {
+		return errors.New("invalid JSON: unexpected end of input")
+	}
+	if strings.IndexByte(chars, s.data[i]) < 0 {
+		return fmt.Errorf("invalid JSON: unexpected %q at offset %d", s.data[i], i)
+	}
+	return nil
+}
+
+// parseValue reads the value at i and returns it with the offset after it
+func (s *jsonSource) parseValue(i int) (*jsonNode, int, error) {
+	if i >= len(s.data) {
+		return nil, 0, errors.New("invalid JSON: unexpected end of input")
+	}
+	n := &jsonNode{start: i}
+	switch c := s.data[i]; c {
+	case '{', '[':
+		n.kind = c
+		closing := byte('}')
+		if c == '[' {
+			closing = ']'
+		}
+		i = s.skipSpace(i + 1)
+		if i < len(s.data) && s.data[i] == closing {
+			n.end = i + 1
+			return n, n.end, nil
+		}
+		for {
+			var m jsonMember
+			if c == '{' {
+				if err := s.expect(i, `"`); err != nil {
+					return nil, 0, err
+				}
+				m.keyStart = i
+				m.keyEnd = s.stringEnd(i)
+				if err := json.Unmarshal(s.data[m.keyStart:m.keyEnd], &m.key); err != nil {
+					return nil, 0, fmt.Errorf("invalid JSON: %w", err)
+				}
+				i = s.skipSpace(m.keyEnd)
+				if err := s.expect(i, ":"); err != nil {
+					return nil, 0, err
+				}
+				s.detectSpacing(i)
+				i = s.skipSpace(i + 1)
+			}
+			value, end, err := s.parseValue(i)
+			if err != nil {
+				return nil, 0, err
+			}
+			if c == '[' {
+				m.keyStart, m.keyEnd = value.start, value.start
+			}
+			m.value = value
+			if len(n.members) == 0 {
+				s.detectIndent(n.start, m.keyStart)
+			}
+			n.members = append(n.members, m)
+			i = s.skipSpace(end)
+			if err := s.expect(i, ","+string(closing)); err != nil {
+				return nil, 0, err
+			}
+			if s.data[i] == closing {
+				n.end = i + 1
+				return n, n.end, nil
+			}
+			s.detectSpacing(i)
+			i = s.skipSpace(i + 1)
+		}
+	case '"':
+		n.end = s.stringEnd(i)
+	default:
+		n.end = i
+		for n.end < len(s.data) && strings.IndexByte(",]} \t\r\n", s.data[n.end]) < 0 {
+			n.end++
+		}
+	}
+	return n, n.end, nil
+}
+
+// stringEnd returns the offset after the string starting at i
+func (s *jsonSource) stringEnd(i int) int {
+	for i++; i < len(s.data); i++ {
+		switch s.data[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return len(s.data)
+}
+
+// detectSpacing sets spaced from the first separator at i that is followed by a space or a value
+func (s *jsonSource) detectSpacing(i int) {
+	if s.spacingFound || i+1 >= len(s.data) || s.data[i+1] == '\n' || s.data[i+1] == '\r' {
+		return
+	}
+	s.spaced, s.spacingFound = s.data[i+1] == ' ', true
+}
+
+// detectIndent sets indent from the first member of the first multi-line object or array
+func (s *jsonSource) detectIndent(start, member int) {
+	if s.indent != "" || bytes.IndexByte(s.data[start:member], '\n') < 0 {
+		return
+	}
+	outer, inner := s.lineIndent(start), s.lineIndent(member)
+	if len(inner) > len(outer) && strings.HasPrefix(inner, outer) {
+		s.indent = inner[len(outer):]
+	}
+}
+
+// lineIndent returns the leading whitespace of the line holding offset
+func (s *jsonSource) lineIndent(offset int) string {
+	start := bytes.LastIndexByte(s.data[:offset], '\n') + 1
+	end := start
+	for end < len(s.data) && (s.data[end] == ' ' || s.data[end] == '\t') {
+		end++
+	}
+	return string(s.data[start:end])
+}
+
+// spans reports whether n is written on several lines
+func (s *jsonSource) spans(n *jsonNode) bool {
+	return bytes.IndexByte(s.data[n.start:n.end], '\n') >= 0
+}
+
+// updateJSON applies update to the value at steps of a JSON document.
+// Only the text of the values that change is rewritten, in the layout of the source.
+func (d *Document) updateJSON(steps []pathStep, update treeUpdate) error {
+	s, err := d.parseJSONSource()
+	if err != nil {
+		return err
+	}
+	root, err := updateTree(d.root, steps, 0, update)
+	if err != nil {
+		return err
+	}
+	if err := d.updateJSONNode(s, s.root, root, s.multiline); err != nil {
+		// updateTree changes the tree in place
+		d.root, _ = parseJSONTree(d.src)
+		return err
+	}
+	sort.Slice(s.edits, func(i, j int) bool { return s.edits[i].start < s.edits[j].start })
+	var src []byte
+	last := 0
+	for _, e := range s.edits {
+		src = append(append(src, s.data[last:e.start]...), e.text...)
+		last = e.end
+	}
+	src = append(src, s.data[last:]...)
+	// Read the output again, so values set by the caller (e.g. structs) can be edited by later calls
+	if d.root, err = parseJSONTree(src); err != nil {
+		return err
+	}
+	d.src = src
+	return nil
+}
+
+// updateJSONNode rewrites the parts of n that differ from v.
+// A rewritten value is written on several lines if n is, or if n is a scalar or empty and multiline is set,
+// which it is for the values of a multi-line object or array.
+func (d *Document) updateJSONNode(s *jsonSource, n *jsonNode, v interface{}, multiline bool) error {
+	switch v := v.(type) {
+	case yaml.MapSlice:
+		if n.kind == '{' {
+			keys := make([]string, len(v))
+			values := make([]interface{}, len(v))
+			for i, item := range v {
+				keys[i], values[i] = fmt.Sprint(item.Key), item.Value
+			}
+			if ok, err := d.updateJSONMembers(s, n, keys, values); ok || err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if n.kind == '[' {
+			if ok, err := d.updateJSONMembers(s, n, make([]string, len(v)), v); ok || err != nil {
+				return err
+			}
+		}
+	default:
+		if n.kind == 0 {
+			if old, err := parseJSONTree(s.data[n.start:n.end]); err == nil && reflect.DeepEqual(old, v) {
+				return nil
+			}
+		}
+	}
+	lines := s.spans(n) || multiline && (n.kind == 0 || len(n.members) == 0)
+	text, err := d.renderJSON(s, v, lines, s.lineIndent(n.start))
+	if err != nil {
+		return err
+	}
+	s.edits = append(s.edits, jsonEdit{start: n.start, end: n.end, text: text})
+	return nil
+}
+
+// updateJSONMembers rewrites the members of n that differ from keys and values, the members of an object
+// or the elements of an array with empty keys. Only a changed value, one added last member or one removed
+// member can be rewritten like this; ok is false for anything else.
+func (d *Document) updateJSONMembers(s *jsonSource, n *jsonNode, keys []string, values []interface{}) (ok bool, err error) {
+	old := n.members
+	same := func(i, j int) bool {
+		if n.kind == '{' {
+			return old[i].key == keys[j]
+		}
+		v, err := parseJSONTree(s.data[old[i].value.start:old[i].value.end])
+		return err == nil && reflect.DeepEqual(v, values[j])
+	}
+	lines := s.spans(n)
+	switch {
+	case len(old) == 0, len(keys) == 0:
+		// Rewritten whole, so an empty object or array gets the layout of its parent
+		return false, nil
+	case len(keys) == len(old), len(keys) == len(old)+1:
+		for i := range old {
+			if n.kind == '{' && !same(i, i) {
+				return false, nil
+			}
+		}
+		for i, m := range old {
+			if err := d.updateJSONNode(s, m.value, values[i], lines); err != nil {
+				return false, err
+			}
+		}
+		if len(keys) == len(old) {
+			return true, nil
+		}
+		return true, d.insertJSONMember(s, n, keys[len(old)], values[len(old)])
+	case len(keys) == len(old)-1:
+		removed := len(keys)
+		for i := range keys {
+			if !same(i, i) {
+				removed = i
+				break
+			}
+		}
+		for i := removed; i < len(keys); i++ {
+			if !same(i+1, i) {
+				return false, nil
+			}
+		}
+		for i, m := range old {
+			if i == removed {
+				continue
+			}
+			j := i
+			if i > removed {
+				j--
+			}
+			if err := d.updateJSONNode(s, m.value, values[j], lines); err != nil {
+				return false, err
+			}
+		}
+		if removed+1 < len(old) {
+			s.edits = append(s.edits, jsonEdit{start: old[removed].keyStart, end: old[removed+1].keyStart})
+		} else {
+			s.edits = append(s.edits, jsonEdit{start: old[removed-1].value.end, end: old[removed].value.end})
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// insertJSONMember adds a member after the last member of n, with the separators of the source
+func (d *Document) insertJSONMember(s *jsonSource, n *jsonNode, key string, value interface{}) error {
+	last := n.members[len(n.members)-1]
+	lines := s.spans(n)
+	indent := s.lineIndent(last.keyStart)
+	var sep string
+	switch {
+	case len(n.members) > 1:
+		sep = string(s.data[n.members[len(n.members)-2].value.end:last.keyStart])
+	case lines:
+		sep = ",\n" + indent
+	case s.spaced:
+		sep = ", "
+	default:
+		sep = ","
+	}
+	text, err := d.renderJSON(s, value, lines, indent)
+	if err != nil {
+		return err
+	}
+	if n.kind == '{' {
+		k, err := json.Marshal(key)
+		if err != nil {
+			return err
+		}
+		text = string(k) + string(s.data[last.keyEnd:last.value.start]) + text
+	}
+	s.edits = append(s.edits, jsonEdit{start: last.value.end, end: last.value.end, text: sep + text})
+	return nil
+}
+
+// renderJSON marshals v with the options of d, on several lines starting with indent if lines is set,
+// otherwise on one line with the spacing of the source
+func (d *Document) renderJSON(s *jsonSource, v interface{}, lines bool, indent string) (string, error) {
+	opts := append(d.opts[:len(d.opts):len(d.opts)], withoutHeader(), withoutFooter(), jsonComments(false))
+	if lines {
+		opts = append(opts, jsonIndent(indent, s.indent))
+	} else {
+		opts = append(opts, jsonIndent("", ""))
+	}
+	out, err := MarshalJSON(v, opts...)
+	if err != nil {
+		return "", err
+	}
+	out = bytes.TrimSuffix(out, []byte("\n"))
+	if !lines && s.spaced {
+		jw := &jsonNodeWriter{spaced: true}
+		if out, err = jw.format(out); err != nil {
+			return "", err
+		}
+	}
+	return string(out), nil
+}
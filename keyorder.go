@@ -0,0 +1,191 @@
+package yamlformat
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// KeyOrder is the order of the keys of mappings written by the encoding functions, set with OrderKeys.
+// By default it applies to Go maps, yaml.MapSlice and OrderedMap values; struct fields keep their
+// declaration order unless WithStructFields is used.
+type KeyOrder struct {
+	priority []string
+	compare  func(a, b string) int // nil keeps the encoded order
+	structs  bool
+}
+
+// AlphabeticalKeys sorts keys alphabetically, which goccy/go-yaml already does for Go maps
+func AlphabeticalKeys() KeyOrder {
+	return KeyOrder{compare: strings.Compare}
+}
+
+// InsertionOrderKeys keeps keys in the order they are encoded: yaml.MapSlice and OrderedMap in insertion
+// order and struct fields in declaration order. Go maps have no insertion order and stay sorted.
+func InsertionOrderKeys() KeyOrder {
+	return KeyOrder{}
+}
+
+// PriorityKeys writes the given keys first, in the given order, and sorts the other keys alphabetically.
+// The keys apply to every mapping, e.g. PriorityKeys("apiVersion", "kind", "metadata", "spec", "name")
+// also writes name first in nested mappings.
+func PriorityKeys(keys ...string) KeyOrder {
+	return AlphabeticalKeys().WithPriority(keys...)
+}
+
+// KeyOrderFunc sorts keys with compare, which returns a negative number if a comes before b,
+// a positive number if a comes after b, and zero to keep their encoded order
+func KeyOrderFunc(compare func(a, b string) int) KeyOrder {
+	return KeyOrder{compare: compare}
+}
+
+// WithPriority returns a copy of o that writes the given keys first, in the given order,
+// before the keys ordered by o
+func (o KeyOrder) WithPriority(keys ...string) KeyOrder {
+	o.priority = append([]string(nil), keys...)
+	return o
+}
+
+// WithStructFields returns a copy of o that orders struct fields too
+func (o KeyOrder) WithStructFields() KeyOrder {
+	o.structs = true
+	return o
+}
+
+// OrderKeys sets the order of mapping keys in YAML and JSON output.
+// Struct fields are told apart from map keys by the marshaled value, so they keep their declaration order
+// unless the order has WithStructFields. This holds for the encoders too, which marshal each value on its own.
+func OrderKeys(order KeyOrder) yaml.EncodeOption {
+	return settingOption(func(p keyOrderProbe) { p.s.keyOrder = &order })
+}
+
+// encodedValue follows pointers and interfaces to the value that goccy/go-yaml encodes as a node.
+// It returns an invalid value for nil and for values written by a marshaler, whose structure is unknown.
+func encodedValue(rv reflect.Value) reflect.Value {
+	for rv.IsValid() {
+		if rv.Type() == jsonObjectType {
+			return rv
+		}
+		if rv.CanInterface() && isMarshaler(rv.Interface()) {
+			return reflect.Value{}
+		}
+		switch rv.Kind() {
+		case reflect.Pointer, reflect.Interface:
+			if rv.IsNil() {
+				return reflect.Value{}
+			}
+			rv = rv.Elem()
+		default:
+			return rv
+		}
+	}
+	return rv
+}
+
+// isMarshaler reports whether goccy/go-yaml encodes v with a marshaler method
+func isMarshaler(v interface{}) bool {
+	switch v.(type) {
+	case yaml.BytesMarshaler, yaml.BytesMarshalerContext, yaml.InterfaceMarshaler, yaml.InterfaceMarshalerContext,
+		encoding.TextMarshaler, json.Marshaler:
+		return true
+	}
+	return false
+}
+
+var mapSliceType = reflect.TypeOf(yaml.MapSlice{})
+
+// mappingOrigin returns whether the mapping encoded from rv is a struct, and a function that
+// returns the value encoded for a key. Unknown values, e.g. written by a marshaler, count as maps.
+func mappingOrigin(rv reflect.Value) (isStruct bool, child func(key string) reflect.Value) {
+	rv = encodedValue(rv)
+	var children map[string]reflect.Value
+	switch {
+	case !rv.IsValid():
+	case rv.Type() == jsonObjectType:
+		o := rv.Interface().(jsonObject)
+		isStruct = o.isStruct
+		children = map[string]reflect.Value{}
+		for _, item := range o.members {
+			children[fmt.Sprint(item.Key)] = reflect.ValueOf(item.Value)
+		}
+	case rv.Type() == mapSliceType:
+		children = map[string]reflect.Value{}
+		for _, item := range rv.Interface().(yaml.MapSlice) {
+			children[fmt.Sprint(item.Key)] = reflect.ValueOf(item.Value)
+		}
+	case rv.Kind() == reflect.Map:
+		children = map[string]reflect.Value{}
+		for iter := rv.MapRange(); iter.Next(); {
+			children[fmt.Sprint(iter.Key().Interface())] = iter.Value()
+		}
+	case rv.Kind() == reflect.Struct:
+		isStruct = true
+		fields := map[string]structField{}
+		structFields(rv, fields)
+		children = make(map[string]reflect.Value, len(fields))
+		for name, f := range fields {
+			children[name] = f.value
+		}
+	}
+	return isStruct, func(key string) reflect.Value { return children[key] }
+}
+
+// structField is a field of a struct as encoded by goccy/go-yaml
+type structField struct {
+	value reflect.Value
+	tag   reflect.StructTag // empty for the entries of an inline map
+}
+
+// structFields adds the fields of a struct to fields by their names in the output,
+// following the rules of goccy/go-yaml: the yaml (or json) tag names a field, the lower-case Go name is
+// the default, and inline fields add their own fields unless a field of the struct has the same name.
+func structFields(rv reflect.Value, fields map[string]structField) {
+	t := rv.Type()
+	var inline []reflect.Value
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("yaml")
+		if tag == "" {
+			tag = f.Tag.Get("json")
+		}
+		if (f.PkgPath != "" && !f.Anonymous) || tag == "-" {
+			continue
+		}
+		options := strings.Split(tag, ",")
+		name := strings.ToLower(f.Name)
+		if options[0] != "" {
+			name = options[0]
+		}
+		isInline := false
+		for _, opt := range options[1:] {
+			isInline = isInline || opt == "inline"
+		}
+		if isInline {
+			inline = append(inline, rv.Field(i))
+			continue
+		}
+		fields[name] = structField{value: rv.Field(i), tag: f.Tag}
+	}
+	for _, fv := range inline {
+		inlined := map[string]structField{}
+		switch v := encodedValue(fv); {
+		case !v.IsValid():
+			continue
+		case v.Kind() == reflect.Struct:
+			structFields(v, inlined)
+		case v.Kind() == reflect.Map:
+			for iter := v.MapRange(); iter.Next(); {
+				inlined[fmt.Sprint(iter.Key().Interface())] = structField{value: iter.Value()}
+			}
+		}
+		for name, field := range inlined {
+			if _, ok := fields[name]; !ok {
+				fields[name] = field
+			}
+		}
+	}
+}
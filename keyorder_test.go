@@ -0,0 +1,218 @@
+package yamlformat
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type keyOrderTestMeta struct {
+	Name   string            `yaml:"name"`
+	Labels map[string]string `yaml:"labels"`
+}
+
+type keyOrderTestInline struct {
+	Zone string `yaml:"zone"`
+	Area string `yaml:"area"`
+}
+
+type keyOrderTestObject struct {
+	Spec     map[string]interface{} `yaml:"spec"`
+	Metadata keyOrderTestMeta       `yaml:"metadata"`
+	Kind     string                 `yaml:"kind"`
+	Extra    keyOrderTestInline     `yaml:",inline"`
+}
+
+var keyOrderTestValue = keyOrderTestObject{
+	Spec: map[string]interface{}{
+		"replicas": 1,
+		"template": map[string]interface{}{"spec": "x", "name": "t", "apiVersion": "v1"},
+		"items":    []interface{}{map[string]interface{}{"b": 1, "name": "i", "a": 2}},
+	},
+	Metadata: keyOrderTestMeta{Name: "web", Labels: map[string]string{"b": "1", "a": "2"}},
+	Kind:     "Deployment",
+	Extra:    keyOrderTestInline{Zone: "z", Area: "a"},
+}
+
+func TestOrderKeys(t *testing.T) {
+	orderedMap := func() *OrderedMap {
+		m := NewOrderedMap()
+		m.Set("zeta", 1)
+		m.Set("name", 2)
+		m.Set("alpha", 3)
+		return m
+	}
+	tests := []struct {
+		name  string
+		value interface{}
+		order KeyOrder
+		want  string
+	}{
+		{
+			name:  "priority keeps struct fields",
+			value: keyOrderTestValue,
+			order: PriorityKeys("apiVersion", "kind", "metadata", "spec", "name"),
+			want: `spec:
+  items:
+  - name: i
+    a: 2
+    b: 1
+  replicas: 1
+  template:
+    apiVersion: v1
+    spec: x
+    name: t
+metadata:
+  name: web
+  labels:
+    a: "2"
+    b: "1"
+kind: Deployment
+zone: z
+area: a
+`,
+		},
+		{
+			name:  "priority with struct fields",
+			value: keyOrderTestValue,
+			order: PriorityKeys("apiVersion", "kind", "metadata", "spec", "name").WithStructFields(),
+			want: `kind: Deployment
+metadata:
+  name: web
+  labels:
+    a: "2"
+    b: "1"
+spec:
+  items:
+  - name: i
+    a: 2
+    b: 1
+  replicas: 1
+  template:
+    apiVersion: v1
+    spec: x
+    name: t
+area: a
+zone: z
+`,
+		},
+		{
+			name:  "comparator",
+			value: map[string]int{"a": 1, "bb": 2, "ccc": 3},
+			order: KeyOrderFunc(func(a, b string) int { return len(b) - len(a) }),
+			want:  "ccc: 3\nbb: 2\na: 1\n",
+		},
+		{
+			name:  "insertion order",
+			value: orderedMap(),
+			order: InsertionOrderKeys(),
+			want:  "zeta: 1\nname: 2\nalpha: 3\n",
+		},
+		{
+			name:  "insertion order with priority",
+			value: orderedMap(),
+			order: InsertionOrderKeys().WithPriority("name"),
+			want:  "name: 2\nzeta: 1\nalpha: 3\n",
+		},
+		{
+			name:  "alphabetical ordered map",
+			value: orderedMap(),
+			order: AlphabeticalKeys(),
+			want:  "alpha: 3\nname: 2\nzeta: 1\n",
+		},
+		{
+			name:  "escapes",
+			value: map[string]string{"o": "tab\there", "q": "é\u00a0\"q\": z", "a": "x"},
+			order: PriorityKeys("q", "o"),
+			want:  "q: \"é\\u00a0\\\"q\\\": z\"\no: tab\there\na: x\n",
+		},
+		{
+			name:  "pointers and interfaces",
+			value: []interface{}{&keyOrderTestMeta{Name: "web", Labels: map[string]string{"b": "1", "a": "2"}}},
+			order: PriorityKeys("b"),
+			want:  "- name: web\n  labels:\n    b: \"1\"\n    a: \"2\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Marshal(tt.value, OrderKeys(tt.order))
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, string(got)); diff != "" {
+				t.Errorf("Marshal() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestOrderKeysJSON(t *testing.T) {
+	got, err := MarshalJSONL(keyOrderTestValue, OrderKeys(PriorityKeys("kind", "name")))
+	if err != nil {
+		t.Fatalf("MarshalJSONL failed: %v", err)
+	}
+	want := `{"spec":{"items":[{"name":"i","a":2,"b":1}],"replicas":1,"template":{"name":"t","apiVersion":"v1","spec":"x"}},"metadata":{"name":"web","labels":{"a":"2","b":"1"}},"kind":"Deployment","zone":"z","area":"a"}` + "\n"
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("MarshalJSONL() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestOrderKeysStreams(t *testing.T) {
+	value := keyOrderTestMeta{Name: "web", Labels: map[string]string{"a": "1", "b": "2"}}
+	opt := OrderKeys(PriorityKeys("labels", "b"))
+	want := "name: web\nlabels:\n  b: \"2\"\n  a: \"1\"\n"
+
+	got, err := MarshalAll([]interface{}{value, value}, opt)
+	if err != nil {
+		t.Fatalf("MarshalAll failed: %v", err)
+	}
+	if diff := cmp.Diff(want+"---\n"+want, string(got)); diff != "" {
+		t.Errorf("MarshalAll() mismatch (-want +got):\n%s", diff)
+	}
+
+	var buf bytes.Buffer
+	enc := NewStreamEncoder(&buf, FormatYAML, StreamDocuments, opt)
+	if err := enc.Encode(value); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("NewStreamEncoder() mismatch (-want +got):\n%s", diff)
+	}
+
+	// yaml.Encoder does not expose the value, so struct fields are ordered too
+	buf.Reset()
+	if err := NewEncoder(&buf, opt).Encode(value); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if want := "labels:\n  b: \"2\"\n  a: \"1\"\nname: web\n"; buf.String() != want {
+		t.Errorf("NewEncoder() wrote %q, want %q", buf.String(), want)
+	}
+
+	// The encoders of the package marshal each value like Marshal, so struct fields keep their order
+	for _, tt := range []struct {
+		name    string
+		newEnc  func(w io.Writer) *Encoder
+		marshal func(v interface{}) ([]byte, error)
+	}{
+		{"NewJSONIndentEncoder", func(w io.Writer) *Encoder { return NewJSONIndentEncoder(w, "", "  ", opt) }, func(v interface{}) ([]byte, error) { return MarshalJSONIndent(v, "", "  ", opt) }},
+		{"NewJSONLEncoder", func(w io.Writer) *Encoder { return NewJSONLEncoder(w, opt) }, func(v interface{}) ([]byte, error) { return MarshalJSONL(v, opt) }},
+	} {
+		buf.Reset()
+		if err := tt.newEnc(&buf).Encode(value); err != nil {
+			t.Fatalf("%s: Encode failed: %v", tt.name, err)
+		}
+		want, err := tt.marshal(value)
+		if err != nil {
+			t.Fatalf("%s: Marshal failed: %v", tt.name, err)
+		}
+		if diff := cmp.Diff(string(want), buf.String()); diff != "" {
+			t.Errorf("%s() mismatch with Marshal (-want +got):\n%s", tt.name, diff)
+		}
+	}
+}
@@ -1,26 +1,505 @@
 package yamlformat
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"math"
+	"math/big"
+	"reflect"
+	"regexp"
 	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/lexer"
+	"github.com/goccy/go-yaml/token"
 )
 
-// marshalFloat64 formats float64 without scientific notation
-func marshalFloat64(v float64) ([]byte, error) {
+// FloatPrecision selects how many digits a FloatFormat writes
+type FloatPrecision int
+
+const (
+	// PrecisionShortest writes the fewest digits that represent the value exactly (default)
+	PrecisionShortest FloatPrecision = iota
+	// PrecisionFixed writes exactly Digits digits after the decimal point
+	PrecisionFixed
+	// PrecisionSignificant writes at most Digits significant digits
+	PrecisionSignificant
+)
+
+// FloatFormat is a policy for formatting float32 and float64 values, including floats in interface{} values.
+// The zero value is the package default: the shortest exact representation without scientific notation.
+type FloatFormat struct {
+	// Precision selects how many digits are written
+	Precision FloatPrecision
+	// Digits is the number of digits for PrecisionFixed and PrecisionSignificant
+	Digits int
+	// ExponentAbove, if positive, switches to scientific notation
+	// for values whose magnitude is greater than or equal to it (e.g. 1e21)
+	ExponentAbove float64
+	// ExponentBelow, if positive, switches to scientific notation
+	// for non-zero values whose magnitude is less than it (e.g. 1e-6)
+	ExponentBelow float64
+}
+
+// FloatFormatter sets the float formatting policy for both YAML and JSON output
+func FloatFormatter(ff FloatFormat) yaml.EncodeOption {
+	return settingOption(func(p floatFormatProbe) { p.s.floatFormat = ff })
+}
+
+// Format formats v, which has the given bit size (32 or 64), according to the policy.
+// NaN and infinities use the YAML spellings .nan, .inf and -.inf.
+func (ff FloatFormat) Format(v float64, bitSize int) string {
 	// Special cases
 	if math.IsNaN(v) {
-		return []byte(".nan"), nil
+		return ".nan"
 	}
 	if math.IsInf(v, 1) {
-		return []byte(".inf"), nil
+		return ".inf"
 	}
 	if math.IsInf(v, -1) {
-		return []byte("-.inf"), nil
+		return "-.inf"
+	}
+
+	abs := math.Abs(v)
+	exponent := (ff.ExponentAbove > 0 && abs >= ff.ExponentAbove) ||
+		(ff.ExponentBelow > 0 && abs != 0 && abs < ff.ExponentBelow)
+
+	switch ff.Precision {
+	case PrecisionFixed:
+		if exponent {
+			return strconv.FormatFloat(v, 'e', ff.Digits, bitSize)
+		}
+		return strconv.FormatFloat(v, 'f', ff.Digits, bitSize)
+	case PrecisionSignificant:
+		if ff.Digits <= 0 {
+			break
+		}
+		rounded := strconv.FormatFloat(v, 'e', ff.Digits-1, bitSize)
+		if exponent {
+			return rounded
+		}
+		// Round to significant digits first, then write without scientific notation
+		v, _ = strconv.ParseFloat(rounded, bitSize)
+	}
+
+	if exponent {
+		return strconv.FormatFloat(v, 'e', -1, bitSize)
 	}
-	
 	// Use strconv.FormatFloat with 'f' format to avoid scientific notation
 	// -1 precision means use the smallest number of digits necessary
-	str := strconv.FormatFloat(v, 'f', -1, 64)
-	
-	return []byte(str), nil
-}
\ No newline at end of file
+	return strconv.FormatFloat(v, 'f', -1, bitSize)
+}
+
+// marshalFloat64 formats float64 without scientific notation
+func marshalFloat64(v float64) ([]byte, error) {
+	return []byte(FloatFormat{}.Format(v, 64)), nil
+}
+
+// marshalFloat32 formats float32 without scientific notation
+func marshalFloat32(v float32) ([]byte, error) {
+	return []byte(FloatFormat{}.Format(float64(v), 32)), nil
+}
+
+// NonFiniteFloatPolicy controls how NaN and infinite floats are written in JSON output,
+// which has no representation for them. YAML output always uses .nan, .inf and -.inf.
+type NonFiniteFloatPolicy int
+
+const (
+	// NonFiniteError fails marshaling with *json.UnsupportedValueError like encoding/json (default)
+	NonFiniteError NonFiniteFloatPolicy = iota
+	// NonFiniteNull writes null
+	NonFiniteNull
+	// NonFiniteString writes the quoted strings "NaN", "Infinity" and "-Infinity" like protojson
+	NonFiniteString
+)
+
+// JSONNonFiniteFloats sets how NaN and infinite floats are written in JSON output.
+// It has no effect on YAML output.
+func JSONNonFiniteFloats(policy NonFiniteFloatPolicy) yaml.EncodeOption {
+	return settingOption(func(p nonFiniteProbe) { p.s.nonFinite = policy })
+}
+
+// floatMarshalers returns custom marshalers for float32 and float64 that apply the settings.
+// For JSON output, non-finite values follow the non-finite policy instead of using the YAML spellings.
+// Without DefaultAutoInt, whole floats keep a trailing ".0" so they are not read back as integers.
+func floatMarshalers(s encodeSettings, jsonOutput bool) []yaml.EncodeOption {
+	if containsDefault(s.without, DefaultFloatFormatter) {
+		return nil
+	}
+	keepFloat := containsDefault(s.without, DefaultAutoInt)
+	marshal := func(v float64, bitSize int) ([]byte, error) {
+		if jsonOutput && (math.IsNaN(v) || math.IsInf(v, 0)) {
+			return marshalJSONNonFinite(v, bitSize, s.nonFinite)
+		}
+		text := s.floatFormat.Format(v, bitSize)
+		if keepFloat && !strings.ContainsAny(text, ".eEn") {
+			text += ".0"
+		}
+		if !jsonOutput {
+			text = yamlNumberLiteral(text)
+		}
+		return []byte(text), nil
+	}
+	return []yaml.EncodeOption{
+		yaml.CustomMarshaler[float32](func(v float32) ([]byte, error) {
+			return marshal(float64(v), 32)
+		}),
+		yaml.CustomMarshaler[float64](func(v float64) ([]byte, error) {
+			return marshal(v, 64)
+		}),
+	}
+}
+
+// marshalJSONNonFinite formats NaN or an infinity for JSON output according to policy
+func marshalJSONNonFinite(v float64, bitSize int, policy NonFiniteFloatPolicy) ([]byte, error) {
+	switch policy {
+	case NonFiniteNull:
+		return []byte("null"), nil
+	case NonFiniteString:
+		switch {
+		case math.IsNaN(v):
+			return []byte(`"NaN"`), nil
+		case v > 0:
+			return []byte(`"Infinity"`), nil
+		default:
+			return []byte(`"-Infinity"`), nil
+		}
+	default:
+		var rv reflect.Value
+		if bitSize == 32 {
+			rv = reflect.ValueOf(float32(v))
+		} else {
+			rv = reflect.ValueOf(v)
+		}
+		return nil, &json.UnsupportedValueError{
+			Value: rv,
+			Str:   strconv.FormatFloat(v, 'g', -1, bitSize),
+		}
+	}
+}
+
+// maxSafeInteger is the largest integer that a JavaScript number (IEEE 754 double) represents exactly
+const maxSafeInteger = 1<<53 - 1
+
+// Int64StringPolicy controls which 64-bit integers (int64, uint64, int and uint) are written as
+// JSON strings, so that consumers which parse numbers as doubles, such as JavaScript, don't lose precision.
+// Use AllowIntegerStrings to decode the strings back into integer fields.
+type Int64StringPolicy int
+
+const (
+	// Int64AsNumber writes all integers as numbers (default)
+	Int64AsNumber Int64StringPolicy = iota
+	// Int64AsStringIfUnsafe writes integers outside ±(2^53-1) as strings
+	Int64AsStringIfUnsafe
+	// Int64AsString writes all 64-bit integers as strings, like protojson
+	Int64AsString
+)
+
+// JSONInt64Strings sets which 64-bit integers are written as strings in JSON output.
+// It has no effect on YAML output.
+func JSONInt64Strings(policy Int64StringPolicy) yaml.EncodeOption {
+	return settingOption(func(p int64StringsProbe) { p.s.int64Strings = policy })
+}
+
+// integerMarshalers returns custom marshalers for 64-bit integer types that apply policy for JSON output
+func integerMarshalers(policy Int64StringPolicy) []yaml.EncodeOption {
+	if policy == Int64AsNumber {
+		return nil
+	}
+	quote := func(s string, safe bool) []byte {
+		if policy == Int64AsStringIfUnsafe && safe {
+			return []byte(s)
+		}
+		return []byte(strconv.Quote(s))
+	}
+	marshalInt := func(v int64) ([]byte, error) {
+		return quote(strconv.FormatInt(v, 10), -maxSafeInteger <= v && v <= maxSafeInteger), nil
+	}
+	marshalUint := func(v uint64) ([]byte, error) {
+		return quote(strconv.FormatUint(v, 10), v <= maxSafeInteger), nil
+	}
+	return []yaml.EncodeOption{
+		yaml.CustomMarshaler[int64](marshalInt),
+		yaml.CustomMarshaler[int](func(v int) ([]byte, error) { return marshalInt(int64(v)) }),
+		yaml.CustomMarshaler[uint64](marshalUint),
+		yaml.CustomMarshaler[uint](func(v uint) ([]byte, error) { return marshalUint(uint64(v)) }),
+	}
+}
+
+// marshalJSONNumber writes json.Number as a plain numeric literal like encoding/json
+func marshalJSONNumber(v json.Number) ([]byte, error) {
+	if v == "" {
+		return []byte("0"), nil
+	}
+	if !isJSONNumber(string(v)) {
+		return nil, fmt.Errorf("invalid number literal %q", string(v))
+	}
+	return []byte(v), nil
+}
+
+// marshalYAMLNumber writes json.Number as a numeric literal that goccy/go-yaml reads back as a number
+func marshalYAMLNumber(v json.Number) ([]byte, error) {
+	b, err := marshalJSONNumber(v)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(yamlNumberLiteral(string(b))), nil
+}
+
+// yamlNumberLiteral returns the number literal s in a form that goccy/go-yaml reads as a number.
+// goccy/go-yaml reads exponents without a fraction, such as 2e5 or 1e+21, as strings,
+// so ".0" is inserted before the exponent.
+func yamlNumberLiteral(s string) string {
+	i := strings.IndexAny(s, "eE")
+	if i < 0 || strings.Contains(s[:i], ".") {
+		return s
+	}
+	return s[:i] + ".0" + s[i:]
+}
+
+// exponentWithoutFraction matches the start of a number in exponent form without a fraction
+var exponentWithoutFraction = regexp.MustCompile(`(^|[^.0-9])[0-9]+[eE][-+]?[0-9]`)
+
+// quoteNumberStrings quotes the plain scalars of the encoded YAML document doc that are numbers in JSON syntax
+// but strings for goccy/go-yaml, such as 2e5. goccy/go-yaml writes such strings without quotes because it
+// reads them back as strings, while YAML 1.2 parsers, Convert and the number policies of DecodeNumbers
+// read them as numbers. Numbers are never written that way: see yamlNumberLiteral.
+func quoteNumberStrings(doc []byte) []byte {
+	if !exponentWithoutFraction.Match(doc) {
+		return doc
+	}
+	var lines []int // byte offsets of the lines
+	for i := -1; i < len(doc); {
+		lines = append(lines, i+1)
+		next := bytes.IndexByte(doc[i+1:], '\n')
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	var out []byte
+	last := 0
+	tokens := lexer.Tokenize(string(doc))
+	for i, tk := range tokens {
+		if tk.Type != token.StringType || !strings.ContainsAny(tk.Value, "eE") || !isJSONNumber(tk.Value) {
+			continue
+		}
+		if i > 0 && (tokens[i-1].Type == token.LiteralType || tokens[i-1].Type == token.FoldedType) {
+			// Content of a block scalar
+			continue
+		}
+		if tk.Position.Line < 1 || tk.Position.Line > len(lines) {
+			continue
+		}
+		start := lines[tk.Position.Line-1]
+		for col := 1; col < tk.Position.Column && start < len(doc); col++ {
+			_, size := utf8.DecodeRune(doc[start:])
+			start += size
+		}
+		if start < last || !bytes.HasPrefix(doc[start:], []byte(tk.Value)) {
+			continue
+		}
+		out = append(out, doc[last:start]...)
+		out = strconv.AppendQuote(out, tk.Value)
+		last = start + len(tk.Value)
+	}
+	if out == nil {
+		return doc
+	}
+	return append(out, doc[last:]...)
+}
+
+// bigNumberMarshalers returns custom marshalers that write math/big numbers as plain numeric literals.
+// They only apply to fields and elements of the big types; numbers stored in interface{} are
+// replaced by bigNumberLiterals before encoding.
+func bigNumberMarshalers() yaml.EncodeOption {
+	return combineEncodeOptions(
+		yaml.CustomMarshaler[*big.Int](marshalBigInt),
+		yaml.CustomMarshaler[big.Int](func(v big.Int) ([]byte, error) { return marshalBigInt(&v) }),
+		yaml.CustomMarshaler[*big.Float](marshalBigFloat),
+		yaml.CustomMarshaler[big.Float](func(v big.Float) ([]byte, error) { return marshalBigFloat(&v) }),
+		yaml.CustomMarshaler[*big.Rat](marshalBigRat),
+		yaml.CustomMarshaler[big.Rat](func(v big.Rat) ([]byte, error) { return marshalBigRat(&v) }),
+	)
+}
+
+// marshalBigInt formats *big.Int as a decimal integer
+func marshalBigInt(v *big.Int) ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+// marshalBigFloat formats *big.Float without scientific notation
+func marshalBigFloat(v *big.Float) ([]byte, error) {
+	if v.IsInf() {
+		return nil, fmt.Errorf("cannot marshal infinite *big.Float: %s", v.String())
+	}
+	return []byte(v.Text('f', -1)), nil
+}
+
+// marshalBigRat formats *big.Rat as an exact decimal number.
+// Rationals without a finite decimal representation such as 1/3 are an error.
+func marshalBigRat(v *big.Rat) ([]byte, error) {
+	if v.IsInt() {
+		return []byte(v.Num().String()), nil
+	}
+	prec, exact := v.FloatPrec()
+	if !exact {
+		return nil, fmt.Errorf("cannot marshal *big.Rat %s exactly as a decimal number", v.String())
+	}
+	return []byte(v.FloatString(prec)), nil
+}
+
+// numberLiteral is a numeric literal written as is, through yaml.BytesMarshaler
+type numberLiteral []byte
+
+// MarshalYAML implements yaml.BytesMarshaler
+func (n numberLiteral) MarshalYAML() ([]byte, error) {
+	return n, nil
+}
+
+// bigNumberLiterals returns v with the math/big numbers stored in interface{} values replaced by numberLiteral.
+// goccy/go-yaml writes such numbers through their encoding.TextMarshaler implementation, as strings,
+// because it looks up the custom marshalers by the interface type. Values without them are returned as is.
+func bigNumberLiterals(v interface{}) (interface{}, error) {
+	rv, changed, err := replaceBigNumbers(reflect.ValueOf(v), false)
+	if err != nil || !changed {
+		return v, err
+	}
+	return rv.Interface(), nil
+}
+
+// replaceBigNumbers replaces the big numbers in interface{} values below rv, which is itself stored
+// in an interface{} value if inInterface is true. Maps, slices, structs and pointers are copied
+// only if something below them is replaced.
+func replaceBigNumbers(rv reflect.Value, inInterface bool) (reflect.Value, bool, error) {
+	if !rv.IsValid() || !rv.CanInterface() {
+		return rv, false, nil
+	}
+	if inInterface {
+		if b, ok, err := marshalBigNumber(rv.Interface()); ok || err != nil {
+			return reflect.ValueOf(numberLiteral(b)), true, err
+		}
+	}
+	if rv.Kind() != reflect.Interface && hasMarshaler(rv) {
+		return rv, false, nil
+	}
+	switch rv.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if rv.IsNil() {
+			return rv, false, nil
+		}
+		elem, changed, err := replaceBigNumbers(rv.Elem(), rv.Kind() == reflect.Interface)
+		if !changed || err != nil {
+			return rv, false, err
+		}
+		if rv.Kind() == reflect.Interface {
+			c := reflect.New(rv.Type()).Elem()
+			c.Set(elem)
+			return c, true, nil
+		}
+		c := reflect.New(elem.Type())
+		c.Elem().Set(elem)
+		return c, true, nil
+	case reflect.Struct:
+		var c reflect.Value
+		for i := 0; i < rv.NumField(); i++ {
+			if !rv.Type().Field(i).IsExported() {
+				continue
+			}
+			field, changed, err := replaceBigNumbers(rv.Field(i), false)
+			if err != nil {
+				return rv, false, err
+			}
+			if changed {
+				if !c.IsValid() {
+					c = reflect.New(rv.Type()).Elem()
+					c.Set(rv)
+				}
+				c.Field(i).Set(field)
+			}
+		}
+		if c.IsValid() {
+			return c, true, nil
+		}
+	case reflect.Map:
+		var c reflect.Value
+		for iter := rv.MapRange(); iter.Next(); {
+			value, changed, err := replaceBigNumbers(iter.Value(), false)
+			if err != nil {
+				return rv, false, err
+			}
+			if changed {
+				if !c.IsValid() {
+					c = reflect.MakeMapWithSize(rv.Type(), rv.Len())
+					for copyIter := rv.MapRange(); copyIter.Next(); {
+						c.SetMapIndex(copyIter.Key(), copyIter.Value())
+					}
+				}
+				c.SetMapIndex(iter.Key(), value)
+			}
+		}
+		if c.IsValid() {
+			return c, true, nil
+		}
+	case reflect.Slice, reflect.Array:
+		var c reflect.Value
+		for i := 0; i < rv.Len(); i++ {
+			elem, changed, err := replaceBigNumbers(rv.Index(i), false)
+			if err != nil {
+				return rv, false, err
+			}
+			if changed {
+				if !c.IsValid() {
+					if rv.Kind() == reflect.Slice {
+						c = reflect.MakeSlice(rv.Type(), rv.Len(), rv.Len())
+						reflect.Copy(c, rv)
+					} else {
+						c = reflect.New(rv.Type()).Elem()
+						c.Set(rv)
+					}
+				}
+				c.Index(i).Set(elem)
+			}
+		}
+		if c.IsValid() {
+			return c, true, nil
+		}
+	}
+	return rv, false, nil
+}
+
+// marshalBigNumber formats v with the marshalers of bigNumberMarshalers if it is a math/big number.
+// Nil pointers are written as null like nil pointer fields.
+func marshalBigNumber(v interface{}) ([]byte, bool, error) {
+	var b []byte
+	var err error
+	switch v := v.(type) {
+	case *big.Int:
+		if v == nil {
+			return []byte("null"), true, nil
+		}
+		b, err = marshalBigInt(v)
+	case big.Int:
+		b, err = marshalBigInt(&v)
+	case *big.Float:
+		if v == nil {
+			return []byte("null"), true, nil
+		}
+		b, err = marshalBigFloat(v)
+	case big.Float:
+		b, err = marshalBigFloat(&v)
+	case *big.Rat:
+		if v == nil {
+			return []byte("null"), true, nil
+		}
+		b, err = marshalBigRat(v)
+	case big.Rat:
+		b, err = marshalBigRat(&v)
+	default:
+		return nil, false, nil
+	}
+	return b, true, err
+}
@@ -0,0 +1,373 @@
+package yamlformat
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNonFiniteFloats(t *testing.T) {
+	input := map[string]interface{}{
+		"nan":     math.NaN(),
+		"neg_inf": math.Inf(-1),
+		"pos_inf": math.Inf(1),
+	}
+
+	tests := []struct {
+		name    string
+		marshal func(v interface{}, opts ...yaml.EncodeOption) ([]byte, error)
+		opts    []yaml.EncodeOption
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "YAML",
+			marshal: Marshal,
+			want:    "nan: .nan\nneg_inf: -.inf\npos_inf: .inf\n",
+		},
+		{
+			name:    "YAML ignores JSON policy",
+			marshal: Marshal,
+			opts:    []yaml.EncodeOption{JSONNonFiniteFloats(NonFiniteNull)},
+			want:    "nan: .nan\nneg_inf: -.inf\npos_inf: .inf\n",
+		},
+		{
+			name:    "JSON defaults to error",
+			marshal: MarshalJSON,
+			wantErr: true,
+		},
+		{
+			name:    "JSON null",
+			marshal: MarshalJSON,
+			opts:    []yaml.EncodeOption{JSONNonFiniteFloats(NonFiniteNull)},
+			want:    `{"nan": null, "neg_inf": null, "pos_inf": null}` + "\n",
+		},
+		{
+			name:    "JSON string",
+			marshal: MarshalJSON,
+			opts:    []yaml.EncodeOption{JSONNonFiniteFloats(NonFiniteString)},
+			want:    `{"nan": "NaN", "neg_inf": "-Infinity", "pos_inf": "Infinity"}` + "\n",
+		},
+		{
+			name:    "later policy wins",
+			marshal: MarshalJSON,
+			opts:    []yaml.EncodeOption{JSONNonFiniteFloats(NonFiniteString), JSONNonFiniteFloats(NonFiniteNull)},
+			want:    `{"nan": null, "neg_inf": null, "pos_inf": null}` + "\n",
+		},
+		{
+			name:    "compact JSON error",
+			marshal: MarshalJSONCompact,
+			wantErr: true,
+		},
+		{
+			name:    "compact JSON null",
+			marshal: MarshalJSONCompact,
+			opts:    []yaml.EncodeOption{JSONNonFiniteFloats(NonFiniteNull)},
+			want:    `{"nan":null,"neg_inf":null,"pos_inf":null}`,
+		},
+		{
+			name:    "JSON Lines string",
+			marshal: MarshalJSONL,
+			opts:    []yaml.EncodeOption{JSONNonFiniteFloats(NonFiniteString)},
+			want:    `{"nan":"NaN","neg_inf":"-Infinity","pos_inf":"Infinity"}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.marshal(input, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("marshal error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				var unsupported *json.UnsupportedValueError
+				if !errors.As(err, &unsupported) {
+					t.Errorf("marshal error = %T, want *json.UnsupportedValueError", err)
+				}
+				return
+			}
+			if string(got) != tt.want {
+				t.Errorf("marshal = %q, want %q", string(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestNonFiniteFloatsEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	encoder := NewJSONEncoder(&buf, JSONNonFiniteFloats(NonFiniteNull))
+	if err := encoder.Encode([]float64{1.5, math.NaN()}); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	want := "[1.5, null]\n"
+	if buf.String() != want {
+		t.Errorf("Encode() = %q, want %q", buf.String(), want)
+	}
+
+	if err := NewJSONEncoder(&buf).Encode(math.Inf(1)); err == nil {
+		t.Error("Encode() of +Inf succeeded, want error")
+	}
+}
+
+func TestFloatFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  FloatFormat
+		input   float64
+		bitSize int
+		want    string
+	}{
+		{name: "default", input: 3.14, bitSize: 64, want: "3.14"},
+		{name: "default whole", input: 100, bitSize: 64, want: "100"},
+		{name: "default large", input: 1e21, bitSize: 64, want: "1000000000000000000000"},
+		{name: "default small", input: 1e-7, bitSize: 64, want: "0.0000001"},
+		{name: "default float32", input: float64(float32(3.14)), bitSize: 32, want: "3.14"},
+		{name: "default NaN", input: math.NaN(), bitSize: 64, want: ".nan"},
+		{
+			name:    "fixed",
+			format:  FloatFormat{Precision: PrecisionFixed, Digits: 2},
+			input:   3.14159,
+			bitSize: 64,
+			want:    "3.14",
+		},
+		{
+			name:    "fixed pads zeros",
+			format:  FloatFormat{Precision: PrecisionFixed, Digits: 2},
+			input:   100,
+			bitSize: 64,
+			want:    "100.00",
+		},
+		{
+			name:    "significant",
+			format:  FloatFormat{Precision: PrecisionSignificant, Digits: 3},
+			input:   123456.789,
+			bitSize: 64,
+			want:    "123000",
+		},
+		{
+			name:    "significant small",
+			format:  FloatFormat{Precision: PrecisionSignificant, Digits: 3},
+			input:   0.000123456,
+			bitSize: 64,
+			want:    "0.000123",
+		},
+		{
+			name:    "exponent above",
+			format:  FloatFormat{ExponentAbove: 1e21},
+			input:   1e300,
+			bitSize: 64,
+			want:    "1e+300",
+		},
+		{
+			name:    "exponent above not reached",
+			format:  FloatFormat{ExponentAbove: 1e21},
+			input:   1e20,
+			bitSize: 64,
+			want:    "100000000000000000000",
+		},
+		{
+			name:    "exponent below",
+			format:  FloatFormat{ExponentBelow: 1e-6},
+			input:   -1.5e-9,
+			bitSize: 64,
+			want:    "-1.5e-09",
+		},
+		{
+			name:    "exponent below ignores zero",
+			format:  FloatFormat{ExponentBelow: 1e-6},
+			input:   0,
+			bitSize: 64,
+			want:    "0",
+		},
+		{
+			name:    "significant with exponent",
+			format:  FloatFormat{Precision: PrecisionSignificant, Digits: 3, ExponentAbove: 1e6},
+			input:   6.02214076e23,
+			bitSize: 64,
+			want:    "6.02e+23",
+		},
+		{
+			name:    "fixed with exponent",
+			format:  FloatFormat{Precision: PrecisionFixed, Digits: 1, ExponentBelow: 0.001},
+			input:   0.00012,
+			bitSize: 64,
+			want:    "1.2e-04",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.format.Format(tt.input, tt.bitSize); got != tt.want {
+				t.Errorf("Format() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFloatFormatter(t *testing.T) {
+	format := FloatFormatter(FloatFormat{Precision: PrecisionFixed, Digits: 2, ExponentAbove: 1e9})
+	input := map[string]interface{}{
+		"float32": float32(1.5),
+		"float64": 2.0,
+		"nested":  []interface{}{float32(1e10), 0.125},
+	}
+
+	tests := []struct {
+		name    string
+		marshal func(v interface{}, opts ...yaml.EncodeOption) ([]byte, error)
+		opts    []yaml.EncodeOption
+		want    string
+	}{
+		{
+			name:    "YAML default",
+			marshal: Marshal,
+			want:    "float32: 1.5\nfloat64: 2\nnested:\n- 10000000000\n- 0.125\n",
+		},
+		{
+			name:    "YAML",
+			marshal: Marshal,
+			opts:    []yaml.EncodeOption{format},
+			want:    "float32: 1.50\nfloat64: 2.00\nnested:\n- 1.00e+10\n- 0.12\n",
+		},
+		{
+			name:    "JSON",
+			marshal: MarshalJSON,
+			opts:    []yaml.EncodeOption{format},
+			want:    `{"float32": 1.50, "float64": 2.00, "nested": [1.00e+10, 0.12]}` + "\n",
+		},
+		{
+			name:    "YAML exponent without fraction",
+			marshal: Marshal,
+			opts:    []yaml.EncodeOption{FloatFormatter(FloatFormat{ExponentAbove: 1e9})},
+			want:    "float32: 1.5\nfloat64: 2\nnested:\n- 1.0e+10\n- 0.125\n",
+		},
+		{
+			name:    "JSON exponent without fraction",
+			marshal: MarshalJSON,
+			opts:    []yaml.EncodeOption{FloatFormatter(FloatFormat{ExponentAbove: 1e9})},
+			want:    `{"float32": 1.5, "float64": 2, "nested": [1e+10, 0.125]}` + "\n",
+		},
+		{
+			name: "YAML with goccy directly",
+			marshal: func(v interface{}, opts ...yaml.EncodeOption) ([]byte, error) {
+				return yaml.MarshalWithOptions(v, WithMarshalOptions(opts...)...)
+			},
+			opts: []yaml.EncodeOption{format},
+			want: "float32: 1.50\nfloat64: 2.00\nnested:\n- 1.00e+10\n- 0.12\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.marshal(input, tt.opts...)
+			if err != nil {
+				t.Fatalf("marshal failed: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("marshal = %q, want %q", string(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestFloatFormatterJSONRoundTrip(t *testing.T) {
+	// Exponents without a fraction such as 1e+300 are read back as numbers
+	format := FloatFormatter(FloatFormat{ExponentAbove: 1e9})
+	input := []interface{}{1e300, 1e10, -2.5e12, 0.125}
+	for _, marshal := range []func(v interface{}, opts ...yaml.EncodeOption) ([]byte, error){MarshalJSON, MarshalJSONCompact} {
+		b, err := marshal(input, format)
+		if err != nil {
+			t.Fatalf("marshal failed: %v", err)
+		}
+		var got interface{}
+		if err := UnmarshalJSON(b, &got); err != nil {
+			t.Fatalf("UnmarshalJSON(%s) failed: %v", b, err)
+		}
+		if diff := cmp.Diff(input, got); diff != "" {
+			t.Errorf("UnmarshalJSON(%s) mismatch (-want +got):\n%s", b, diff)
+		}
+	}
+}
+
+func TestFloat32NonFinite(t *testing.T) {
+	got, err := MarshalJSON([]float32{float32(math.Inf(-1))}, JSONNonFiniteFloats(NonFiniteString))
+	if err != nil {
+		t.Fatalf("MarshalJSON failed: %v", err)
+	}
+	if want := `["-Infinity"]` + "\n"; string(got) != want {
+		t.Errorf("MarshalJSON() = %q, want %q", got, want)
+	}
+
+	if _, err := MarshalJSON(float32(math.NaN())); err == nil {
+		t.Error("MarshalJSON() of float32 NaN succeeded, want error")
+	}
+}
+
+func TestJSONInt64Strings(t *testing.T) {
+	input := map[string]interface{}{
+		"safe":     int64(42),
+		"unsafe":   int64(1<<53 + 1),
+		"negative": -(1<<53 + 1),
+		"uint":     uint64(18446744073709551615),
+		"small":    int32(7),
+		"float":    1.5,
+	}
+
+	tests := []struct {
+		name    string
+		marshal func(v interface{}, opts ...yaml.EncodeOption) ([]byte, error)
+		opts    []yaml.EncodeOption
+		want    string
+	}{
+		{
+			name:    "numbers by default",
+			marshal: MarshalJSONCompact,
+			want:    `{"float":1.5,"negative":-9007199254740993,"safe":42,"small":7,"uint":18446744073709551615,"unsafe":9007199254740993}`,
+		},
+		{
+			name:    "strings if unsafe",
+			marshal: MarshalJSONCompact,
+			opts:    []yaml.EncodeOption{JSONInt64Strings(Int64AsStringIfUnsafe)},
+			want:    `{"float":1.5,"negative":"-9007199254740993","safe":42,"small":7,"uint":"18446744073709551615","unsafe":"9007199254740993"}`,
+		},
+		{
+			name:    "all 64-bit integers as strings",
+			marshal: MarshalJSONCompact,
+			opts:    []yaml.EncodeOption{JSONInt64Strings(Int64AsString)},
+			want:    `{"float":1.5,"negative":"-9007199254740993","safe":"42","small":7,"uint":"18446744073709551615","unsafe":"9007199254740993"}`,
+		},
+		{
+			name:    "YAML is not affected",
+			marshal: Marshal,
+			opts:    []yaml.EncodeOption{JSONInt64Strings(Int64AsString)},
+			want:    "float: 1.5\nnegative: -9007199254740993\nsafe: 42\nsmall: 7\nuint: 18446744073709551615\nunsafe: 9007199254740993\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.marshal(input, tt.opts...)
+			if err != nil {
+				t.Fatalf("marshal failed: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("marshal = %q, want %q", string(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONInt64StringsMapKeys(t *testing.T) {
+	got, err := MarshalJSONCompact(map[int64]string{1: "a"}, JSONInt64Strings(Int64AsString))
+	if err != nil {
+		t.Fatalf("MarshalJSONCompact failed: %v", err)
+	}
+	if want := `{"1":"a"}`; string(got) != want {
+		t.Errorf("MarshalJSONCompact() = %q, want %q", got, want)
+	}
+}
@@ -1,23 +1,333 @@
 package yamlformat
 
-import "github.com/goccy/go-yaml"
+import (
+	"encoding/json"
+	"io"
+	"reflect"
 
-// defaultMarshalOptions returns a copy of the default marshal options
-func defaultMarshalOptions() []yaml.EncodeOption {
-	return append([]yaml.EncodeOption{}, marshalOptions...)
+	"github.com/goccy/go-yaml"
+)
+
+// Default names one of the package's default options
+type Default string
+
+const (
+	// DefaultJSONMarshaler is yaml.UseJSONMarshaler() and yaml.UseJSONUnmarshaler()
+	DefaultJSONMarshaler Default = "JSONMarshaler"
+	// DefaultAutoInt is yaml.AutoInt(), which writes whole floats as integers (100.0 → 100)
+	DefaultAutoInt Default = "AutoInt"
+	// DefaultLiteralMultiline is yaml.UseLiteralStyleIfMultiline(true)
+	DefaultLiteralMultiline Default = "LiteralMultiline"
+	// DefaultFloatFormatter is the float32/float64 marshalers configured by FloatFormatter and JSONNonFiniteFloats
+	DefaultFloatFormatter Default = "FloatFormatter"
+	// DefaultBigNumbers is the math/big and json.Number marshalers and the math/big unmarshalers
+	DefaultBigNumbers Default = "BigNumbers"
+)
+
+// defaultOption is a named default with its encoding and decoding options (either may be nil)
+type defaultOption struct {
+	name   Default
+	encode yaml.EncodeOption
+	decode yaml.DecodeOption
+}
+
+// Options is a set of options built on the package defaults.
+// The zero value is equivalent to DefaultOptions(). Options values are immutable;
+// Without, With and WithDecode return a modified copy.
+// Use MarshalFormat and NewFormatEncoder (e.g. with FormatJSON) for JSON output.
+type Options struct {
+	without []Default
+	encode  []yaml.EncodeOption
+	decode  []yaml.DecodeOption
+}
+
+// DefaultOptions returns the package defaults
+func DefaultOptions() Options {
+	return Options{}
+}
+
+// Defaults returns the names of the default options that are enabled, in the order they are applied
+func (o Options) Defaults() []Default {
+	var names []Default
+	for _, d := range defaultOptions {
+		if !containsDefault(o.without, d.name) {
+			names = append(names, d.name)
+		}
+	}
+	return names
+}
+
+// Without returns a copy of o with the named defaults removed.
+// Without(DefaultAutoInt) keeps whole floats distinguishable by writing them with a trailing ".0".
+// Without(DefaultFloatFormatter) restores goccy/go-yaml float formatting, so FloatFormatter
+// and JSONNonFiniteFloats no longer apply.
+func (o Options) Without(defaults ...Default) Options {
+	o.without = append(append([]Default{}, o.without...), defaults...)
+	return o
+}
+
+// With returns a copy of o with opts appended to its encoding options
+func (o Options) With(opts ...yaml.EncodeOption) Options {
+	o.encode = append(append([]yaml.EncodeOption{}, o.encode...), opts...)
+	return o
+}
+
+// WithDecode returns a copy of o with opts appended to its decoding options
+func (o Options) WithDecode(opts ...yaml.DecodeOption) Options {
+	o.decode = append(append([]yaml.DecodeOption{}, o.decode...), opts...)
+	return o
+}
+
+// encodeOptions returns the options that carry o into the package functions, followed by opts
+func (o Options) encodeOptions(opts []yaml.EncodeOption) []yaml.EncodeOption {
+	without := o.without
+	allOpts := []yaml.EncodeOption{settingOption(func(p withoutEncodeProbe) { p.s.without = without })}
+	allOpts = append(allOpts, o.encode...)
+	return append(allOpts, opts...)
+}
+
+// decodeOptions returns the options that carry o into the package functions, followed by opts
+func (o Options) decodeOptions(opts []yaml.DecodeOption) []yaml.DecodeOption {
+	without := o.without
+	allOpts := []yaml.DecodeOption{decodeSettingOption(func(p withoutDecodeProbe) { p.s.without = without })}
+	allOpts = append(allOpts, o.decode...)
+	return append(allOpts, opts...)
+}
+
+// MarshalOptions returns the complete options for YAML output with goccy/go-yaml directly.
+// Settings that rewrite the encoded output, such as the key order of a profile, need the package functions.
+func (o Options) MarshalOptions() []yaml.EncodeOption {
+	return yamlEncodeConfig(o.encodeOptions(nil)).opts
+}
+
+// UnmarshalOptions returns the complete options for decoding with goccy/go-yaml directly
+func (o Options) UnmarshalOptions() []yaml.DecodeOption {
+	return allUnmarshalOptions(o.decodeOptions(nil))
+}
+
+// Marshal is like Marshal but uses o instead of the package defaults
+func (o Options) Marshal(v interface{}, opts ...yaml.EncodeOption) ([]byte, error) {
+	return Marshal(v, o.encodeOptions(opts)...)
+}
+
+// Unmarshal is like Unmarshal but uses o instead of the package defaults
+func (o Options) Unmarshal(data []byte, v interface{}, opts ...yaml.DecodeOption) error {
+	return Unmarshal(data, v, o.decodeOptions(opts)...)
+}
+
+// NewEncoder is like NewEncoder but uses o instead of the package defaults
+func (o Options) NewEncoder(w io.Writer, opts ...yaml.EncodeOption) *yaml.Encoder {
+	return NewEncoder(w, o.encodeOptions(opts)...)
+}
+
+// NewDecoder is like NewDecoder but uses o instead of the package defaults
+func (o Options) NewDecoder(r io.Reader, opts ...yaml.DecodeOption) *yaml.Decoder {
+	return NewDecoder(r, o.decodeOptions(opts)...)
+}
+
+// MarshalFormat is like f.Marshal but uses o instead of the package defaults
+func (o Options) MarshalFormat(f Format, v interface{}, opts ...yaml.EncodeOption) ([]byte, error) {
+	return f.Marshal(v, o.encodeOptions(opts)...)
+}
+
+// UnmarshalFormat is like f.Unmarshal but uses o instead of the package defaults
+func (o Options) UnmarshalFormat(f Format, data []byte, v interface{}, opts ...yaml.DecodeOption) error {
+	return f.Unmarshal(data, v, o.decodeOptions(opts)...)
+}
+
+// NewFormatEncoder is like f.NewEncoder but uses o instead of the package defaults
+func (o Options) NewFormatEncoder(f Format, w io.Writer, opts ...yaml.EncodeOption) *yaml.Encoder {
+	return f.NewEncoder(w, o.encodeOptions(opts)...)
+}
+
+// NewFormatDecoder is like f.NewDecoder but uses o instead of the package defaults
+func (o Options) NewFormatDecoder(f Format, r io.Reader, opts ...yaml.DecodeOption) ValueDecoder {
+	return f.NewDecoder(r, o.decodeOptions(opts)...)
+}
+
+// containsDefault reports whether names contains name
+func containsDefault(names []Default, name Default) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeConfig is the resolved configuration of an encoding function
+type encodeConfig struct {
+	settings encodeSettings
+	opts     []yaml.EncodeOption
+	json     bool
+}
+
+// jsonEncodeConfig returns the configuration for JSON output with the default options followed by opts
+func jsonEncodeConfig(opts []yaml.EncodeOption) encodeConfig {
+	settings := loadEncodeSettings(opts)
+	allOpts := settings.defaultMarshalOptions()
+	allOpts = append(allOpts, yaml.JSON())
+	allOpts = append(allOpts, floatMarshalers(settings, true)...)
+	allOpts = append(allOpts, integerMarshalers(settings.int64Strings)...)
+	return encodeConfig{settings: settings, opts: append(allOpts, opts...), json: true}
 }
 
-// defaultUnmarshalOptions returns a copy of the default unmarshal options
-func defaultUnmarshalOptions() []yaml.DecodeOption {
-	return append([]yaml.DecodeOption{}, unmarshalOptions...)
+// yamlEncodeConfig returns the configuration for YAML output with the default options followed by opts
+func yamlEncodeConfig(opts []yaml.EncodeOption) encodeConfig {
+	settings := loadEncodeSettings(opts)
+	allOpts := settings.defaultMarshalOptions()
+	allOpts = append(allOpts, floatMarshalers(settings, false)...)
+	if !containsDefault(settings.without, DefaultBigNumbers) {
+		allOpts = append(allOpts, yaml.CustomMarshaler[json.Number](marshalYAMLNumber))
+	}
+	return encodeConfig{settings: settings, opts: append(allOpts, opts...)}
 }
 
-// WithMarshalOptions creates a new set of options by appending to defaults
+// marshal encodes v as a single document
+func (c encodeConfig) marshal(v interface{}) ([]byte, error) {
+	opts := c.opts
+	var rootComment string
+	if !c.json {
+		cm := c.settings.commentMap(reflect.ValueOf(v))
+		if c.settings.reshapes() {
+			cm, rootComment = detachRootComment(cm)
+		}
+		if len(cm) > 0 {
+			opts = append(opts[:len(opts):len(opts)], yaml.WithComment(cm))
+		}
+	}
+	encoded := v
+	if !containsDefault(c.settings.without, DefaultBigNumbers) {
+		var err error
+		if encoded, err = bigNumberLiterals(v); err != nil {
+			return nil, err
+		}
+	}
+	b, err := yaml.MarshalWithOptions(encoded, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if !c.json && !containsDefault(c.settings.without, DefaultFloatFormatter) {
+		// Without the float formatter, goccy/go-yaml writes floats like 1e+21 that quoteNumberStrings would quote
+		b = quoteNumberStrings(b)
+	}
+	if !c.settings.reshapes() {
+		return b, nil
+	}
+	b, err = reshape(b, c.settings, reflect.ValueOf(v))
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(rootComment), b...), nil
+}
+
+// newEncoder creates a yaml.Encoder that writes to w.
+// If rewrite is not nil, each document is passed through it and document separators are dropped.
+func (c encodeConfig) newEncoder(w io.Writer, rewrite func(doc []byte) ([]byte, error)) *yaml.Encoder {
+	dropSeparators := rewrite != nil
+	// Struct tags are not seen, as the values are encoded by yaml.Encoder
+	comments := c.settings.comments
+	var rootComment string
+	quote := !c.json && !containsDefault(c.settings.without, DefaultFloatFormatter)
+	if c.settings.reshapes() && !c.json {
+		comments, rootComment = detachRootComment(comments)
+	}
+	if quote || c.settings.reshapes() {
+		next := rewrite
+		rewrite = func(doc []byte) ([]byte, error) {
+			// The rewrites of marshal
+			if quote {
+				doc = quoteNumberStrings(doc)
+			}
+			if c.settings.reshapes() {
+				var err error
+				if doc, err = reshape(doc, c.settings, reflect.Value{}); err != nil {
+					return nil, err
+				}
+				doc = append([]byte(rootComment), doc...)
+			}
+			if next == nil {
+				return doc, nil
+			}
+			return next(doc)
+		}
+	}
+	opts := c.opts
+	if !c.json && len(comments) > 0 {
+		opts = append(opts[:len(opts):len(opts)], yaml.WithComment(comments))
+	}
+	if rewrite == nil {
+		return yaml.NewEncoder(w, opts...)
+	}
+	return yaml.NewEncoder(&documentWriter{w: w, rewrite: rewrite, dropSeparators: dropSeparators}, opts...)
+}
+
+// allUnmarshalOptions returns the default options for unmarshaling followed by opts
+func allUnmarshalOptions(opts []yaml.DecodeOption) []yaml.DecodeOption {
+	settings := loadDecodeSettings(opts)
+	allOpts := settings.defaultUnmarshalOptions()
+	if opt := interfaceUnmarshaler(settings); opt != nil {
+		allOpts = append(allOpts, opt)
+	}
+	allOpts = append(allOpts, orderedMapUnmarshaler(settings))
+	return append(allOpts, opts...)
+}
+
+// defaultMarshalOptions returns the enabled default marshal options
+func (s encodeSettings) defaultMarshalOptions() []yaml.EncodeOption {
+	var opts []yaml.EncodeOption
+	for _, d := range defaultOptions {
+		if d.encode != nil && !containsDefault(s.without, d.name) {
+			opts = append(opts, d.encode)
+		}
+	}
+	return opts
+}
+
+// defaultUnmarshalOptions returns the enabled default unmarshal options
+func (s decodeSettings) defaultUnmarshalOptions() []yaml.DecodeOption {
+	var opts []yaml.DecodeOption
+	for _, d := range defaultOptions {
+		if d.decode != nil && !containsDefault(s.without, d.name) {
+			opts = append(opts, d.decode)
+		}
+	}
+	return opts
+}
+
+// WithMarshalOptions creates a new set of options by appending to defaults.
+// The result is for YAML output with goccy/go-yaml directly.
+// It is a shorthand for DefaultOptions().With(opts...).MarshalOptions().
 func WithMarshalOptions(opts ...yaml.EncodeOption) []yaml.EncodeOption {
-	return append(defaultMarshalOptions(), opts...)
+	return DefaultOptions().With(opts...).MarshalOptions()
 }
 
-// WithUnmarshalOptions creates a new set of options by appending to defaults
+// WithUnmarshalOptions creates a new set of options by appending to defaults.
+// It is a shorthand for DefaultOptions().WithDecode(opts...).UnmarshalOptions().
 func WithUnmarshalOptions(opts ...yaml.DecodeOption) []yaml.DecodeOption {
-	return append(defaultUnmarshalOptions(), opts...)
-}
\ No newline at end of file
+	return DefaultOptions().WithDecode(opts...).UnmarshalOptions()
+}
+
+// combineEncodeOptions returns a single option that applies opts in order
+func combineEncodeOptions(opts ...yaml.EncodeOption) yaml.EncodeOption {
+	return func(e *yaml.Encoder) error {
+		for _, opt := range opts {
+			if err := opt(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// combineDecodeOptions returns a single option that applies opts in order
+func combineDecodeOptions(opts ...yaml.DecodeOption) yaml.DecodeOption {
+	return func(d *yaml.Decoder) error {
+		for _, opt := range opts {
+			if err := opt(d); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,169 @@
+package yamlformat
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestOptionsDefaults(t *testing.T) {
+	want := []Default{DefaultJSONMarshaler, DefaultAutoInt, DefaultLiteralMultiline, DefaultFloatFormatter, DefaultBigNumbers}
+	if diff := cmp.Diff(want, DefaultOptions().Defaults()); diff != "" {
+		t.Errorf("DefaultOptions().Defaults() mismatch (-want +got):\n%s", diff)
+	}
+
+	got := DefaultOptions().Without(DefaultAutoInt, DefaultBigNumbers).Defaults()
+	want = []Default{DefaultJSONMarshaler, DefaultLiteralMultiline, DefaultFloatFormatter}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Without().Defaults() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestOptionsWithout(t *testing.T) {
+	data := map[string]interface{}{
+		"float": 100.0,
+		"half":  2.5,
+		"text":  "line1\nline2",
+	}
+
+	tests := []struct {
+		name   string
+		opts   Options
+		format Format
+		want   string
+	}{
+		{
+			name:   "defaults",
+			opts:   DefaultOptions(),
+			format: FormatYAML,
+			want:   "float: 100\nhalf: 2.5\ntext: |-\n  line1\n  line2\n",
+		},
+		{
+			name:   "without AutoInt",
+			opts:   DefaultOptions().Without(DefaultAutoInt),
+			format: FormatYAML,
+			want:   "float: 100.0\nhalf: 2.5\ntext: |-\n  line1\n  line2\n",
+		},
+		{
+			name:   "without AutoInt JSON",
+			opts:   DefaultOptions().Without(DefaultAutoInt),
+			format: FormatJSON,
+			want:   "{\"float\": 100.0, \"half\": 2.5, \"text\": \"line1\\nline2\"}\n",
+		},
+		{
+			name:   "with",
+			opts:   DefaultOptions().With(FloatFormatter(FloatFormat{Precision: PrecisionFixed, Digits: 2})),
+			format: FormatYAML,
+			want:   "float: 100.00\nhalf: 2.50\ntext: |-\n  line1\n  line2\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.opts.MarshalFormat(tt.format, data)
+			if err != nil {
+				t.Fatalf("MarshalFormat failed: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("MarshalFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOptionsWithoutFloatFormatter(t *testing.T) {
+	got, err := DefaultOptions().Without(DefaultFloatFormatter).Marshal(map[string]float64{"v": 1e21})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if want := "v: 1e+21\n"; string(got) != want {
+		t.Errorf("Marshal() = %q, want %q", got, want)
+	}
+}
+
+func TestOptionsImmutable(t *testing.T) {
+	base := DefaultOptions().Without(DefaultAutoInt)
+	_ = base.Without(DefaultLiteralMultiline)
+	_ = base.With(yaml.Indent(4))
+
+	got, err := base.Marshal(map[string]interface{}{"a": []int{1}})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if want := "a:\n- 1\n"; string(got) != want {
+		t.Errorf("Marshal() = %q, want %q", got, want)
+	}
+}
+
+func TestOptionsDecode(t *testing.T) {
+	var v struct {
+		N *big.Int `yaml:"n"`
+	}
+	if err := DefaultOptions().Unmarshal([]byte("n: 123456789012345678901234567890"), &v); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if got, want := v.N.String(), "123456789012345678901234567890"; got != want {
+		t.Errorf("Unmarshal() = %s, want %s", got, want)
+	}
+
+	got, err := DefaultOptions().Without(DefaultBigNumbers).Marshal(v)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if want := `"123456789012345678901234567890"`; !strings.Contains(string(got), want) {
+		t.Errorf("Marshal() without BigNumbers = %q, want it to contain %s", got, want)
+	}
+
+	var n interface{}
+	opts := DefaultOptions().WithDecode(DecodeNumbers(NumberInt64Float64))
+	if err := opts.UnmarshalFormat(FormatJSON, []byte("[1]"), &n); err != nil {
+		t.Fatalf("UnmarshalFormat failed: %v", err)
+	}
+	if diff := cmp.Diff([]interface{}{int64(1)}, n); diff != "" {
+		t.Errorf("UnmarshalFormat() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestOptionsEncoderDecoder(t *testing.T) {
+	opts := DefaultOptions().Without(DefaultAutoInt)
+
+	var buf bytes.Buffer
+	enc := opts.NewFormatEncoder(FormatJSONL, &buf)
+	for _, v := range []float64{1, 2.5} {
+		if err := enc.Encode(map[string]float64{"v": v}); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+	if want := "{\"v\":1.0}\n{\"v\":2.5}\n"; buf.String() != want {
+		t.Errorf("NewFormatEncoder() wrote %q, want %q", buf.String(), want)
+	}
+
+	dec := opts.NewFormatDecoder(FormatJSONL, strings.NewReader(buf.String()))
+	var got []float64
+	for {
+		var v struct {
+			V float64 `yaml:"v"`
+		}
+		if err := dec.Decode(&v); err != nil {
+			break
+		}
+		got = append(got, v.V)
+	}
+	if diff := cmp.Diff([]float64{1, 2.5}, got); diff != "" {
+		t.Errorf("NewFormatDecoder() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestOptionsMarshalOptions(t *testing.T) {
+	got, err := yaml.MarshalWithOptions(map[string]float64{"v": 3}, DefaultOptions().Without(DefaultAutoInt).MarshalOptions()...)
+	if err != nil {
+		t.Fatalf("MarshalWithOptions failed: %v", err)
+	}
+	if want := "v: 3.0\n"; string(got) != want {
+		t.Errorf("MarshalWithOptions() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,130 @@
+package yamlformat
+
+import (
+	"fmt"
+	"iter"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/parser"
+)
+
+// OrderedMap is a mapping with string keys that keeps its keys in insertion order.
+// Decoding into an OrderedMap keeps the order of the source, including in nested mappings,
+// and Marshal, MarshalJSON and the other encoding functions write the keys in that order.
+// The zero value is an empty map ready to use.
+type OrderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// NewOrderedMap creates an empty OrderedMap
+func NewOrderedMap() *OrderedMap {
+	return &OrderedMap{}
+}
+
+// Get returns the value of key and whether the key is present
+func (m *OrderedMap) Get(key string) (interface{}, bool) {
+	v, ok := m.values[key]
+	return v, ok
+}
+
+// Set sets the value of key. A new key is added at the end; an existing key keeps its position.
+func (m *OrderedMap) Set(key string, value interface{}) {
+	if m.values == nil {
+		m.values = map[string]interface{}{}
+	}
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+// Delete removes key and reports whether it was present
+func (m *OrderedMap) Delete(key string) bool {
+	if _, ok := m.values[key]; !ok {
+		return false
+	}
+	delete(m.values, key)
+	for i, k := range m.keys {
+		if k == key {
+			m.keys = append(m.keys[:i:i], m.keys[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// Keys returns the keys in order
+func (m *OrderedMap) Keys() []string {
+	return append([]string(nil), m.keys...)
+}
+
+// Len returns the number of keys
+func (m *OrderedMap) Len() int {
+	return len(m.keys)
+}
+
+// All returns an iterator over the keys and values in order
+func (m *OrderedMap) All() iter.Seq2[string, interface{}] {
+	return func(yield func(string, interface{}) bool) {
+		for _, key := range m.keys {
+			if !yield(key, m.values[key]) {
+				return
+			}
+		}
+	}
+}
+
+// MarshalYAML returns the keys and values as a yaml.MapSlice, which goccy/go-yaml encodes in order
+func (m OrderedMap) MarshalYAML() (interface{}, error) {
+	items := make(yaml.MapSlice, len(m.keys))
+	for i, key := range m.keys {
+		items[i] = yaml.MapItem{Key: key, Value: m.values[key]}
+	}
+	return items, nil
+}
+
+// UnmarshalYAML decodes a mapping in source order, with nested mappings as *OrderedMap.
+// The package decoding functions decode OrderedMap with their own settings instead,
+// e.g. DecodeNumbers.
+func (m *OrderedMap) UnmarshalYAML(b []byte) error {
+	return decodeOrderedMap(m, b, decodeSettings{})
+}
+
+// DecodeOrderedMaps decodes mappings in interface{} values, including nested ones, as *OrderedMap
+// in source order instead of map[string]interface{}
+func DecodeOrderedMaps() yaml.DecodeOption {
+	return decodeSettingOption(func(p orderedMapsProbe) { p.s.orderedMaps = true })
+}
+
+// orderedMapUnmarshaler returns a custom unmarshaler for OrderedMap that applies the settings
+func orderedMapUnmarshaler(s decodeSettings) yaml.DecodeOption {
+	return yaml.CustomUnmarshaler[OrderedMap](func(m *OrderedMap, b []byte) error {
+		return decodeOrderedMap(m, b, s)
+	})
+}
+
+// decodeOrderedMap decodes a mapping into m, with nested mappings as *OrderedMap
+func decodeOrderedMap(m *OrderedMap, b []byte, s decodeSettings) error {
+	s.orderedMaps = true
+	file, err := parser.ParseBytes(b, 0, s.parseOptions()...)
+	if err != nil {
+		return err
+	}
+	*m = OrderedMap{}
+	if len(file.Docs) == 0 || file.Docs[0].Body == nil {
+		return nil
+	}
+	value, err := s.nodeToValue(file.Docs[0].Body)
+	if err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case *OrderedMap:
+		*m = *v
+	case nil:
+	default:
+		return fmt.Errorf("cannot unmarshal %T into OrderedMap", value)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package yamlformat
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+	"github.com/google/go-cmp/cmp"
+)
+
+const orderedMapTestYAML = `name: web
+items:
+- zone: b
+  area: a
+count: 2
+`
+
+func TestOrderedMap(t *testing.T) {
+	var m OrderedMap
+	m.Set("b", 1)
+	m.Set("a", 2)
+	m.Set("c", 3)
+	m.Set("b", 4)
+	if diff := cmp.Diff([]string{"b", "a", "c"}, m.Keys()); diff != "" {
+		t.Errorf("Keys() mismatch (-want +got):\n%s", diff)
+	}
+	if v, ok := m.Get("b"); !ok || v != 4 {
+		t.Errorf("Get(b) = %v, %v, want 4, true", v, ok)
+	}
+	if _, ok := m.Get("x"); ok {
+		t.Error("Get(x) found a missing key")
+	}
+	if !m.Delete("a") || m.Delete("a") {
+		t.Error("Delete(a) = false for a present key, or true for a deleted key")
+	}
+	m.Set("a", 5)
+	var keys []string
+	var values []interface{}
+	for k, v := range m.All() {
+		keys = append(keys, k)
+		values = append(values, v)
+	}
+	if diff := cmp.Diff([]string{"b", "c", "a"}, keys); diff != "" {
+		t.Errorf("All() keys mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]interface{}{4, 3, 5}, values); diff != "" {
+		t.Errorf("All() values mismatch (-want +got):\n%s", diff)
+	}
+	if m.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", m.Len())
+	}
+
+	got, err := Marshal(&m)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if want := "b: 4\nc: 3\na: 5\n"; string(got) != want {
+		t.Errorf("Marshal() = %q, want %q", got, want)
+	}
+}
+
+func TestOrderedMapRoundTrip(t *testing.T) {
+	m, err := UnmarshalAs[OrderedMap]([]byte(orderedMapTestYAML))
+	if err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if diff := cmp.Diff([]string{"name", "items", "count"}, m.Keys()); diff != "" {
+		t.Errorf("Keys() mismatch (-want +got):\n%s", diff)
+	}
+	if count, _ := m.Get("count"); count != uint64(2) {
+		t.Errorf("Get(count) = %#v, want uint64(2)", count)
+	}
+
+	got, err := Marshal(m)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if diff := cmp.Diff(orderedMapTestYAML, string(got)); diff != "" {
+		t.Errorf("Marshal() mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err = MarshalJSON(m)
+	if err != nil {
+		t.Fatalf("MarshalJSON failed: %v", err)
+	}
+	if want := `{"name": "web", "items": [{"zone": "b", "area": "a"}], "count": 2}` + "\n"; string(got) != want {
+		t.Errorf("MarshalJSON() = %q, want %q", got, want)
+	}
+}
+
+func TestDecodeOrderedMaps(t *testing.T) {
+	var v interface{}
+	if err := Unmarshal([]byte(orderedMapTestYAML), &v, DecodeOrderedMaps()); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	m, ok := v.(*OrderedMap)
+	if !ok {
+		t.Fatalf("Unmarshal() = %T, want *OrderedMap", v)
+	}
+	items, _ := m.Get("items")
+	item, ok := items.([]interface{})[0].(*OrderedMap)
+	if !ok {
+		t.Fatalf("nested mapping = %T, want *OrderedMap", items.([]interface{})[0])
+	}
+	if diff := cmp.Diff([]string{"zone", "area"}, item.Keys()); diff != "" {
+		t.Errorf("nested Keys() mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err := MarshalJSONCompact(v)
+	if err != nil {
+		t.Fatalf("MarshalJSONCompact failed: %v", err)
+	}
+	if want := `{"name":"web","items":[{"zone":"b","area":"a"}],"count":2}`; string(got) != want {
+		t.Errorf("MarshalJSONCompact() = %q, want %q", got, want)
+	}
+}
+
+func TestOrderedMapDecodeSettings(t *testing.T) {
+	var v struct {
+		Config  OrderedMap  `yaml:"config"`
+		Pointer *OrderedMap `yaml:"pointer"`
+	}
+	input := "config:\n  big: 123456789012345678901234567890\n  base: &base {x: 1}\n  merged:\n    <<: *base\n    y: 2\npointer: {b: 1, a: 2}\n"
+	if err := Unmarshal([]byte(input), &v, DecodeNumbers(NumberJSONNumber)); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if big, _ := v.Config.Get("big"); big != json.Number("123456789012345678901234567890") {
+		t.Errorf("Get(big) = %#v, want json.Number", big)
+	}
+	merged, _ := v.Config.Get("merged")
+	if diff := cmp.Diff([]string{"x", "y"}, merged.(*OrderedMap).Keys()); diff != "" {
+		t.Errorf("merged Keys() mismatch (-want +got):\n%s", diff)
+	}
+	if v.Pointer == nil {
+		t.Fatal("pointer field is nil")
+	}
+	if diff := cmp.Diff([]string{"b", "a"}, v.Pointer.Keys()); diff != "" {
+		t.Errorf("pointer Keys() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestOrderedMapGoccy(t *testing.T) {
+	var m OrderedMap
+	if err := yaml.Unmarshal([]byte("b: 1\na: {d: 1, c: 2}\n"), &m); err != nil {
+		t.Fatalf("yaml.Unmarshal failed: %v", err)
+	}
+	got, err := yaml.Marshal(m)
+	if err != nil {
+		t.Fatalf("yaml.Marshal failed: %v", err)
+	}
+	if want := "b: 1\na:\n  d: 1\n  c: 2\n"; string(got) != want {
+		t.Errorf("yaml.Marshal() = %q, want %q", got, want)
+	}
+
+	if err := yaml.Unmarshal([]byte("[1]"), &m); err == nil {
+		t.Error("yaml.Unmarshal() of a sequence succeeded, want error")
+	}
+}
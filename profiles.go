@@ -0,0 +1,71 @@
+package yamlformat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Profile names accepted by Profile
+const (
+	// ProfileKubernetes indents sequences and writes apiVersion, kind and metadata first in every mapping
+	ProfileKubernetes = "kubernetes"
+	// ProfileCompact writes nested collections of up to compactFlowMaxItems scalars in flow style
+	ProfileCompact = "compact"
+	// ProfileHuman indents JSON output and sequences. Like the defaults, it writes multi-line strings
+	// as literal blocks and never wraps lines.
+	ProfileHuman = "human"
+	// ProfileCanonical sorts all keys, including struct fields, writes JSON output without whitespace,
+	// and normalizes numbers decoded into interface{} with NumberAutoInt
+	ProfileCanonical = "canonical"
+)
+
+// compactFlowMaxItems is the largest collection written in flow style by ProfileCompact
+const compactFlowMaxItems = 8
+
+// profiles maps profile names to functions that build the profile
+var profiles = map[string]func() Options{
+	ProfileKubernetes: func() Options {
+		return DefaultOptions().With(
+			yaml.IndentSequence(true),
+			OrderKeys(InsertionOrderKeys().WithPriority("apiVersion", "kind", "metadata").WithStructFields()),
+		)
+	},
+	ProfileCompact: func() Options {
+		return DefaultOptions().With(flowSmallCollections(compactFlowMaxItems))
+	},
+	ProfileHuman: func() Options {
+		return DefaultOptions().With(
+			yaml.IndentSequence(true),
+			jsonIndent("", prettyJSONIndent),
+		)
+	},
+	ProfileCanonical: func() Options {
+		return DefaultOptions().With(
+			OrderKeys(AlphabeticalKeys().WithStructFields()),
+			jsonIndent("", ""),
+		).WithDecode(DecodeNumbers(NumberAutoInt))
+	},
+}
+
+// Profile returns the named formatting profile (case-insensitive), built on the package defaults.
+// The result can be extended with With, WithDecode and Without like any Options.
+func Profile(name string) (Options, error) {
+	build, ok := profiles[strings.ToLower(name)]
+	if !ok {
+		return Options{}, fmt.Errorf("invalid profile: %s (valid: %s)", name, strings.Join(ProfileNames(), ", "))
+	}
+	return build(), nil
+}
+
+// ProfileNames returns the names of the profiles in sorted order
+func ProfileNames() []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,181 @@
+package yamlformat
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type profileTestMetadata struct {
+	Name   string            `yaml:"name"`
+	Labels map[string]string `yaml:"labels"`
+}
+
+type profileTestObject struct {
+	Spec       map[string]interface{} `yaml:"spec"`
+	Metadata   profileTestMetadata    `yaml:"metadata"`
+	Kind       string                 `yaml:"kind"`
+	APIVersion string                 `yaml:"apiVersion"`
+}
+
+var profileTestValue = profileTestObject{
+	Spec: map[string]interface{}{
+		"replicas": 3,
+		"ports":    []int{80, 443},
+		"args":     []string{"a, b", "c"},
+		"script":   "echo 1\necho 2\n",
+	},
+	Metadata:   profileTestMetadata{Name: "web", Labels: map[string]string{"app": "web"}},
+	Kind:       "Deployment",
+	APIVersion: "apps/v1",
+}
+
+func TestProfile(t *testing.T) {
+	tests := []struct {
+		profile string
+		format  Format
+		want    string
+	}{
+		{
+			profile: ProfileKubernetes,
+			format:  FormatYAML,
+			want: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels:
+    app: web
+spec:
+  args:
+    - a, b
+    - c
+  ports:
+    - 80
+    - 443
+  replicas: 3
+  script: |
+    echo 1
+    echo 2
+`,
+		},
+		{
+			profile: ProfileKubernetes,
+			format:  FormatJSON,
+			want:    `{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "web", "labels": {"app": "web"}}, "spec": {"args": ["a, b", "c"], "ports": [80, 443], "replicas": 3, "script": "echo 1\necho 2\n"}}` + "\n",
+		},
+		{
+			profile: ProfileCompact,
+			format:  FormatYAML,
+			want: `spec:
+  args:
+  - a, b
+  - c
+  ports: [80, 443]
+  replicas: 3
+  script: |
+    echo 1
+    echo 2
+metadata:
+  name: web
+  labels: {app: web}
+kind: Deployment
+apiVersion: apps/v1
+`,
+		},
+		{
+			profile: ProfileHuman,
+			format:  FormatJSON,
+			want: `{
+  "spec": {
+    "args": [
+      "a, b",
+      "c"
+    ],
+    "ports": [
+      80,
+      443
+    ],
+    "replicas": 3,
+    "script": "echo 1\necho 2\n"
+  },
+  "metadata": {
+    "name": "web",
+    "labels": {
+      "app": "web"
+    }
+  },
+  "kind": "Deployment",
+  "apiVersion": "apps/v1"
+}
+`,
+		},
+		{
+			profile: ProfileCanonical,
+			format:  FormatJSON,
+			want:    `{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"labels":{"app":"web"},"name":"web"},"spec":{"args":["a, b","c"],"ports":[80,443],"replicas":3,"script":"echo 1\necho 2\n"}}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.profile+"/"+string(tt.format), func(t *testing.T) {
+			opts, err := Profile(tt.profile)
+			if err != nil {
+				t.Fatalf("Profile failed: %v", err)
+			}
+			got, err := opts.MarshalFormat(tt.format, profileTestValue)
+			if err != nil {
+				t.Fatalf("MarshalFormat failed: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, string(got)); diff != "" {
+				t.Errorf("MarshalFormat() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestProfileEncoder(t *testing.T) {
+	opts, err := Profile("Kubernetes")
+	if err != nil {
+		t.Fatalf("Profile failed: %v", err)
+	}
+	var buf bytes.Buffer
+	enc := opts.NewEncoder(&buf)
+	for _, kind := range []string{"Service", "Deployment"} {
+		if err := enc.Encode(map[string]string{"apiVersion": "v1", "kind": kind, "data": "x"}); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+	want := "apiVersion: v1\nkind: Service\ndata: x\n---\napiVersion: v1\nkind: Deployment\ndata: x\n"
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("NewEncoder() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestProfileCanonicalDecode(t *testing.T) {
+	opts, err := Profile(ProfileCanonical)
+	if err != nil {
+		t.Fatalf("Profile failed: %v", err)
+	}
+	var v interface{}
+	if err := opts.Unmarshal([]byte("b: 100.0\na: 1.50"), &v); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	got, err := opts.MarshalFormat(FormatJSON, v)
+	if err != nil {
+		t.Fatalf("MarshalFormat failed: %v", err)
+	}
+	if want := `{"a":1.5,"b":100}` + "\n"; string(got) != want {
+		t.Errorf("MarshalFormat() = %q, want %q", got, want)
+	}
+}
+
+func TestProfileInvalid(t *testing.T) {
+	_, err := Profile("fancy")
+	if err == nil {
+		t.Fatal("Profile() succeeded, want error")
+	}
+	if want := "invalid profile: fancy (valid: canonical, compact, human, kubernetes)"; err.Error() != want {
+		t.Errorf("Profile() error = %q, want %q", err, want)
+	}
+}
@@ -0,0 +1,276 @@
+package yamlformat
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/goccy/go-yaml"
+)
+
+// ValueEncoder writes one value per call to Encode, like *yaml.Encoder, *Encoder or *json.Encoder.
+// If it implements io.Closer, Encoder.Close closes it.
+type ValueEncoder interface {
+	Encode(v interface{}) error
+}
+
+// ValueDecoder reads one value per call to Decode, like *yaml.Decoder or *json.Decoder.
+// Decode returns io.EOF after the last value.
+type ValueDecoder interface {
+	Decode(v interface{}) error
+}
+
+// FormatSpec describes a format for RegisterFormat.
+// The functions receive the caller's options; a format built on this package should pass them to
+// the package functions (Marshal, NewJSONLEncoder, Unmarshal, ...) so the package defaults and Options apply,
+// and any other format may ignore them.
+type FormatSpec struct {
+	// Name is the canonical name of the format, which is also its Format value. It must be lower case.
+	Name Format
+	// Aliases are alternative names accepted by ParseFormat (e.g. "yml")
+	Aliases []string
+	// Extensions are file extensions including the leading dot (e.g. ".yaml")
+	Extensions []string
+	// MediaTypes are MIME types without parameters (e.g. "application/yaml")
+	MediaTypes []string
+
+	Marshal    func(v interface{}, opts ...yaml.EncodeOption) ([]byte, error)
+	NewEncoder func(w io.Writer, opts ...yaml.EncodeOption) ValueEncoder
+	Unmarshal  func(data []byte, v interface{}, opts ...yaml.DecodeOption) error
+	NewDecoder func(r io.Reader, opts ...yaml.DecodeOption) ValueDecoder
+
+	// newYAMLEncoder creates the yaml.Encoder of Format.NewEncoder for a built-in format
+	newYAMLEncoder func(w io.Writer, opts ...yaml.EncodeOption) *yaml.Encoder
+}
+
+// formatRegistry holds the registered formats
+type formatRegistry struct {
+	mu         sync.RWMutex
+	specs      []*FormatSpec // in registration order
+	byName     map[string]*FormatSpec
+	byExt      map[string]*FormatSpec
+	byMedia    map[string]*FormatSpec
+	aliasNames map[string]bool
+}
+
+// registry is the format registry, with the built-in formats registered first
+var registry = newFormatRegistry(builtinFormats()...)
+
+// builtinFormats returns the specs of the built-in formats
+func builtinFormats() []FormatSpec {
+	return []FormatSpec{
+		{
+			Name:           FormatYAML,
+			Aliases:        []string{"yml"},
+			Extensions:     []string{".yaml", ".yml"},
+			MediaTypes:     []string{"application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml"},
+			Marshal:        Marshal,
+			NewEncoder:     documentsEncoder(FormatYAML),
+			Unmarshal:      Unmarshal,
+			NewDecoder:     yamlDecoder,
+			newYAMLEncoder: NewEncoder,
+		},
+		{
+			Name:           FormatJSON,
+			Extensions:     []string{".json"},
+			MediaTypes:     []string{"application/json"},
+			Marshal:        MarshalJSON,
+			NewEncoder:     documentsEncoder(FormatJSON),
+			Unmarshal:      UnmarshalJSON,
+			NewDecoder:     jsonDecoder,
+			newYAMLEncoder: NewJSONEncoder,
+		},
+		{
+			Name: FormatJSONPretty,
+			Marshal: func(v interface{}, opts ...yaml.EncodeOption) ([]byte, error) {
+				return MarshalJSONIndent(v, "", prettyJSONIndent, opts...)
+			},
+			NewEncoder: documentsEncoder(FormatJSONPretty),
+			Unmarshal:  UnmarshalJSON,
+			NewDecoder: jsonDecoder,
+			newYAMLEncoder: func(w io.Writer, opts ...yaml.EncodeOption) *yaml.Encoder {
+				return NewJSONEncoder(w, append(opts[:len(opts):len(opts)], jsonIndent("", prettyJSONIndent))...)
+			},
+		},
+		{
+			Name:           FormatJSONL,
+			Aliases:        []string{"ndjson"},
+			Extensions:     []string{".jsonl", ".ndjson"},
+			MediaTypes:     []string{"application/jsonl", "application/x-ndjson", "application/x-jsonlines"},
+			Marshal:        MarshalJSONL,
+			NewEncoder:     documentsEncoder(FormatJSONL),
+			Unmarshal:      UnmarshalJSONL,
+			NewDecoder:     jsonLinesDecoder,
+			newYAMLEncoder: newJSONLinesEncoder,
+		},
+	}
+}
+
+// documentsEncoder returns the NewEncoder function of a built-in format: an unbuffered Encoder in StreamDocuments mode
+func documentsEncoder(format Format) func(w io.Writer, opts ...yaml.EncodeOption) ValueEncoder {
+	return func(w io.Writer, opts ...yaml.EncodeOption) ValueEncoder {
+		return newValueEncoder(w, newDocumentsLayout(format, opts))
+	}
+}
+
+// yamlDecoder is the NewDecoder function of FormatYAML
+func yamlDecoder(r io.Reader, opts ...yaml.DecodeOption) ValueDecoder {
+	return NewDecoder(r, opts...)
+}
+
+// jsonDecoder is the NewDecoder function of FormatJSON and FormatJSONPretty
+func jsonDecoder(r io.Reader, opts ...yaml.DecodeOption) ValueDecoder {
+	return NewJSONDecoder(r, opts...)
+}
+
+// jsonLinesDecoder is the NewDecoder function of FormatJSONL
+func jsonLinesDecoder(r io.Reader, opts ...yaml.DecodeOption) ValueDecoder {
+	return NewJSONLDecoder(r, opts...)
+}
+
+func newFormatRegistry(specs ...FormatSpec) *formatRegistry {
+	r := &formatRegistry{
+		byName:     map[string]*FormatSpec{},
+		byExt:      map[string]*FormatSpec{},
+		byMedia:    map[string]*FormatSpec{},
+		aliasNames: map[string]bool{},
+	}
+	for _, spec := range specs {
+		if err := r.register(spec); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
+
+// RegisterFormat adds a format to the registry, so ParseFormat, IsValid and the Format methods support it.
+// Aliases, extensions and media types are matched case-insensitively.
+// It returns an error if the spec is incomplete or any of its names is already registered.
+func RegisterFormat(spec FormatSpec) error {
+	return registry.register(spec)
+}
+
+func (r *formatRegistry) register(spec FormatSpec) error {
+	if spec.Name == "" {
+		return errors.New("register format: empty name")
+	}
+	if string(spec.Name) != strings.ToLower(string(spec.Name)) {
+		return fmt.Errorf("register format %s: name must be lower case", spec.Name)
+	}
+	if spec.Marshal == nil || spec.NewEncoder == nil || spec.Unmarshal == nil || spec.NewDecoder == nil {
+		return fmt.Errorf("register format %s: Marshal, NewEncoder, Unmarshal and NewDecoder are required", spec.Name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := append([]string{string(spec.Name)}, lowerAll(spec.Aliases)...)
+	for _, name := range names {
+		if _, ok := r.byName[name]; ok {
+			return fmt.Errorf("register format %s: name %s is already registered", spec.Name, name)
+		}
+	}
+	exts := lowerAll(spec.Extensions)
+	for _, ext := range exts {
+		if !strings.HasPrefix(ext, ".") {
+			return fmt.Errorf("register format %s: extension %s must start with a dot", spec.Name, ext)
+		}
+		if _, ok := r.byExt[ext]; ok {
+			return fmt.Errorf("register format %s: extension %s is already registered", spec.Name, ext)
+		}
+	}
+	mediaTypes := lowerAll(spec.MediaTypes)
+	for _, mt := range mediaTypes {
+		if _, ok := r.byMedia[mt]; ok {
+			return fmt.Errorf("register format %s: media type %s is already registered", spec.Name, mt)
+		}
+	}
+
+	s := spec
+	s.Aliases, s.Extensions, s.MediaTypes = names[1:], exts, mediaTypes
+	r.specs = append(r.specs, &s)
+	for _, name := range names {
+		r.byName[name] = &s
+	}
+	for _, alias := range s.Aliases {
+		r.aliasNames[alias] = true
+	}
+	for _, ext := range exts {
+		r.byExt[ext] = &s
+	}
+	for _, mt := range mediaTypes {
+		r.byMedia[mt] = &s
+	}
+	return nil
+}
+
+// lookup returns the spec of a canonical format name
+func (r *formatRegistry) lookup(f Format) (*FormatSpec, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	spec, ok := r.byName[string(f)]
+	if !ok || r.aliasNames[string(f)] {
+		return nil, false
+	}
+	return spec, true
+}
+
+// parse returns the format named by a canonical name or alias (case-insensitive)
+func (r *formatRegistry) parse(s string) (Format, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	spec, ok := r.byName[strings.ToLower(s)]
+	if !ok {
+		return "", false
+	}
+	return spec.Name, true
+}
+
+// byExtension returns the format registered for a file extension including the leading dot
+func (r *formatRegistry) byExtension(ext string) (Format, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	spec, ok := r.byExt[strings.ToLower(ext)]
+	if !ok {
+		return "", false
+	}
+	return spec.Name, true
+}
+
+// byMediaType returns the format registered for a media type without parameters
+func (r *formatRegistry) byMediaType(mediaType string) (Format, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	spec, ok := r.byMedia[strings.ToLower(mediaType)]
+	if !ok {
+		return "", false
+	}
+	return spec.Name, true
+}
+
+// names returns the canonical names of the registered formats in registration order
+func (r *formatRegistry) names() []Format {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]Format, len(r.specs))
+	for i, spec := range r.specs {
+		names[i] = spec.Name
+	}
+	return names
+}
+
+// Formats returns the registered formats in registration order, starting with the built-in formats
+func Formats() []Format {
+	return registry.names()
+}
+
+// lowerAll returns the strings in lower case
+func lowerAll(ss []string) []string {
+	lower := make([]string, len(ss))
+	for i, s := range ss {
+		lower[i] = strings.ToLower(s)
+	}
+	return lower
+}
@@ -0,0 +1,170 @@
+package yamlformat_test
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/apstndb/go-yamlformat"
+	"github.com/goccy/go-yaml"
+	"github.com/google/go-cmp/cmp"
+)
+
+// kvSpec registers a format of "key=value" lines for flat objects, as a package outside yamlformat would.
+// Each value is a block of lines, and blocks are separated by a blank line.
+var kvSpec = yamlformat.FormatSpec{
+	Name:       "kv",
+	Extensions: []string{".kv"},
+	MediaTypes: []string{"text/x-kv"},
+	Marshal: func(v interface{}, _ ...yaml.EncodeOption) ([]byte, error) {
+		return marshalKV(v)
+	},
+	NewEncoder: func(w io.Writer, _ ...yaml.EncodeOption) yamlformat.ValueEncoder {
+		return &kvEncoder{w: w}
+	},
+	Unmarshal: func(data []byte, v interface{}, _ ...yaml.DecodeOption) error {
+		return (&kvDecoder{s: bufio.NewScanner(bytes.NewReader(data))}).Decode(v)
+	},
+	NewDecoder: func(r io.Reader, _ ...yaml.DecodeOption) yamlformat.ValueDecoder {
+		return &kvDecoder{s: bufio.NewScanner(r)}
+	},
+}
+
+// marshalKV writes the fields of a flat object as sorted "key=value" lines
+func marshalKV(v interface{}) ([]byte, error) {
+	var fields map[string]interface{}
+	b, err := yamlformat.MarshalJSON(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := yamlformat.UnmarshalJSON(b, &fields); err != nil {
+		return nil, fmt.Errorf("kv: %w", err)
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var buf bytes.Buffer
+	for _, k := range keys {
+		fmt.Fprintf(&buf, "%s=%v\n", k, fields[k])
+	}
+	return buf.Bytes(), nil
+}
+
+type kvEncoder struct {
+	w     io.Writer
+	count int
+}
+
+func (e *kvEncoder) Encode(v interface{}) error {
+	b, err := marshalKV(v)
+	if err != nil {
+		return err
+	}
+	if e.count > 0 {
+		b = append([]byte("\n"), b...)
+	}
+	e.count++
+	_, err = e.w.Write(b)
+	return err
+}
+
+type kvDecoder struct {
+	s *bufio.Scanner
+}
+
+func (d *kvDecoder) Decode(v interface{}) error {
+	fields := map[string]string{}
+	for d.s.Scan() {
+		line := d.s.Text()
+		if line == "" {
+			if len(fields) == 0 {
+				continue
+			}
+			break
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("kv: invalid line %q", line)
+		}
+		fields[key] = value
+	}
+	if err := d.s.Err(); err != nil {
+		return err
+	}
+	if len(fields) == 0 {
+		return io.EOF
+	}
+	b, err := yamlformat.MarshalJSON(fields)
+	if err != nil {
+		return err
+	}
+	return yamlformat.Unmarshal(b, v)
+}
+
+func TestRegisterFormatOutsidePackage(t *testing.T) {
+	yamlformat.WithTestRegistry(t)
+	if err := yamlformat.RegisterFormat(kvSpec); err != nil {
+		t.Fatalf("RegisterFormat failed: %v", err)
+	}
+
+	kv, err := yamlformat.ParseFormat("KV")
+	if err != nil {
+		t.Fatalf("ParseFormat failed: %v", err)
+	}
+	if got, ok := yamlformat.FormatFromPath("settings.kv"); !ok || got != kv {
+		t.Errorf("FormatFromPath() = %v, %v, want %v", got, ok, kv)
+	}
+
+	type record struct {
+		Name string `yaml:"name"`
+		Zone string `yaml:"zone"`
+	}
+	records := []record{{Name: "web", Zone: "a"}, {Name: "db", Zone: "b"}}
+	want := "name=web\nzone=a\n\nname=db\nzone=b\n"
+
+	var buf bytes.Buffer
+	enc := yamlformat.NewStreamEncoder(&buf, kv, yamlformat.StreamDocuments)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("NewStreamEncoder() mismatch (-want +got):\n%s", diff)
+	}
+
+	// Format.NewEncoder returns a yaml.Encoder, which writes each value with the Marshal function of the format
+	buf.Reset()
+	yamlEnc := kv.NewEncoder(&buf)
+	if err := yamlEnc.Encode(records[0]); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if want := "name=web\nzone=a\n"; buf.String() != want {
+		t.Errorf("Format.NewEncoder() wrote %q, want %q", buf.String(), want)
+	}
+
+	got, err := yamlformat.DecodeAll[record](strings.NewReader(want), kv)
+	if err != nil {
+		t.Fatalf("DecodeAll failed: %v", err)
+	}
+	if diff := cmp.Diff(records, got); diff != "" {
+		t.Errorf("DecodeAll() mismatch (-want +got):\n%s", diff)
+	}
+
+	converted, err := yamlformat.Convert([]byte(want), kv, yamlformat.FormatJSONL)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if want := "{\"name\":\"web\",\"zone\":\"a\"}\n{\"name\":\"db\",\"zone\":\"b\"}\n"; string(converted) != want {
+		t.Errorf("Convert() = %q, want %q", converted, want)
+	}
+}
@@ -0,0 +1,167 @@
+package yamlformat
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+	"github.com/google/go-cmp/cmp"
+)
+
+// withTestRegistry replaces the registry with one holding only the built-in formats for the duration of the test
+func withTestRegistry(t *testing.T) {
+	t.Helper()
+	saved := registry
+	registry = newFormatRegistry(builtinFormats()...)
+	t.Cleanup(func() { registry = saved })
+}
+
+// flowYAMLSpec is a test format that writes YAML in flow style
+var flowYAMLSpec = FormatSpec{
+	Name:       "yaml-flow",
+	Aliases:    []string{"flow", "FYAML"},
+	Extensions: []string{".fyaml"},
+	MediaTypes: []string{"application/x-flow-yaml"},
+	Marshal: func(v interface{}, opts ...yaml.EncodeOption) ([]byte, error) {
+		return Marshal(v, append([]yaml.EncodeOption{yaml.Flow(true)}, opts...)...)
+	},
+	NewEncoder: func(w io.Writer, opts ...yaml.EncodeOption) ValueEncoder {
+		return NewEncoder(w, append([]yaml.EncodeOption{yaml.Flow(true)}, opts...)...)
+	},
+	Unmarshal: Unmarshal,
+	NewDecoder: func(r io.Reader, opts ...yaml.DecodeOption) ValueDecoder {
+		return NewDecoder(r, opts...)
+	},
+}
+
+func TestRegisterFormat(t *testing.T) {
+	withTestRegistry(t)
+
+	const flow Format = "yaml-flow"
+	if flow.IsValid() {
+		t.Fatalf("IsValid() = true before RegisterFormat")
+	}
+	if err := RegisterFormat(flowYAMLSpec); err != nil {
+		t.Fatalf("RegisterFormat failed: %v", err)
+	}
+	if !flow.IsValid() {
+		t.Errorf("IsValid() = false after RegisterFormat")
+	}
+
+	for _, name := range []string{"yaml-flow", "flow", "fyaml", "FLOW"} {
+		got, err := ParseFormat(name)
+		if err != nil {
+			t.Errorf("ParseFormat(%q) failed: %v", name, err)
+		} else if got != flow {
+			t.Errorf("ParseFormat(%q) = %v, want %v", name, got, flow)
+		}
+	}
+	if Format("flow").IsValid() {
+		t.Errorf("IsValid() = true for an alias, want false")
+	}
+
+	want := []Format{FormatYAML, FormatJSON, FormatJSONPretty, FormatJSONL, flow}
+	if diff := cmp.Diff(want, Formats()); diff != "" {
+		t.Errorf("Formats() mismatch (-want +got):\n%s", diff)
+	}
+
+	data := map[string]interface{}{"a": 1, "b": []int{1, 2}}
+	got, err := flow.Marshal(data)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if want := "{a: 1, b: [1, 2]}\n"; string(got) != want {
+		t.Errorf("Marshal() = %q, want %q", got, want)
+	}
+
+	var buf bytes.Buffer
+	if err := flow.NewEncoder(&buf).Encode(data); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if want := "{a: 1, b: [1, 2]}\n"; buf.String() != want {
+		t.Errorf("Encode() wrote %q, want %q", buf.String(), want)
+	}
+
+	var decoded map[string]interface{}
+	if err := flow.NewDecoder(strings.NewReader("{a: 1}")).Decode(&decoded); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if diff := cmp.Diff(map[string]interface{}{"a": uint64(1)}, decoded); diff != "" {
+		t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
+	}
+
+	_, err = ParseFormat("xml")
+	if want := "invalid format: xml (valid: yaml, json, json-pretty, jsonl, yaml-flow)"; err == nil || err.Error() != want {
+		t.Errorf("ParseFormat() error = %v, want %q", err, want)
+	}
+}
+
+func TestRegisterFormatErrors(t *testing.T) {
+	withTestRegistry(t)
+
+	spec := func(modify func(*FormatSpec)) FormatSpec {
+		s := flowYAMLSpec
+		modify(&s)
+		return s
+	}
+	tests := []struct {
+		name string
+		spec FormatSpec
+		want string
+	}{
+		{
+			name: "empty name",
+			spec: spec(func(s *FormatSpec) { s.Name = "" }),
+			want: "register format: empty name",
+		},
+		{
+			name: "upper case name",
+			spec: spec(func(s *FormatSpec) { s.Name = "Flow" }),
+			want: "register format Flow: name must be lower case",
+		},
+		{
+			name: "missing function",
+			spec: spec(func(s *FormatSpec) { s.NewDecoder = nil }),
+			want: "register format yaml-flow: Marshal, NewEncoder, Unmarshal and NewDecoder are required",
+		},
+		{
+			name: "duplicate name",
+			spec: spec(func(s *FormatSpec) { s.Name = FormatJSON }),
+			want: "register format json: name json is already registered",
+		},
+		{
+			name: "duplicate alias",
+			spec: spec(func(s *FormatSpec) { s.Aliases = []string{"YML"} }),
+			want: "register format yaml-flow: name yml is already registered",
+		},
+		{
+			name: "duplicate extension",
+			spec: spec(func(s *FormatSpec) { s.Extensions = []string{".JSON"} }),
+			want: "register format yaml-flow: extension .json is already registered",
+		},
+		{
+			name: "extension without dot",
+			spec: spec(func(s *FormatSpec) { s.Extensions = []string{"fyaml"} }),
+			want: "register format yaml-flow: extension fyaml must start with a dot",
+		},
+		{
+			name: "duplicate media type",
+			spec: spec(func(s *FormatSpec) { s.MediaTypes = []string{"application/yaml"} }),
+			want: "register format yaml-flow: media type application/yaml is already registered",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := RegisterFormat(tt.spec)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("RegisterFormat() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+	if len(Formats()) != 4 {
+		t.Errorf("Formats() = %v after failed registrations, want the built-in formats", Formats())
+	}
+}
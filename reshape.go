@@ -0,0 +1,310 @@
+package yamlformat
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/goccy/go-yaml/token"
+)
+
+// flowSmallCollections writes nested sequences and mappings of at most maxItems scalars in flow style
+func flowSmallCollections(maxItems int) yaml.EncodeOption {
+	return settingOption(func(p flowProbe) { p.s.flowMaxItems = maxItems })
+}
+
+// reshapes reports whether encoded documents are rewritten by reshape
+func (s encodeSettings) reshapes() bool {
+	return s.keyOrder != nil || s.flowMaxItems > 0
+}
+
+// reshape rewrites an encoded document to apply the key order and flow style settings.
+// The document is parsed, and the text of its mapping entries is moved and the text of its small collections
+// is rewritten in flow style, so everything else (quoting, escapes, number formatting, literal blocks and
+// comments) is kept as encoded. v is the encoded value, which tells struct fields from map keys; it is invalid if unknown.
+func reshape(doc []byte, s encodeSettings, v reflect.Value) ([]byte, error) {
+	file, err := parser.ParseBytes(doc, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	if len(file.Docs) != 1 {
+		return nil, fmt.Errorf("want exactly one document, got %d", len(file.Docs))
+	}
+	r := &reshaper{s: s, src: newYAMLSource(doc, file)}
+	if r.src.body == nil {
+		return doc, nil
+	}
+	if s.keyOrder == nil {
+		// The value is only needed for the key order
+		v = reflect.Value{}
+	}
+	r.reshapeNode(r.src.body, v, r.src.trimEnd(0, len(doc), 0))
+	return []byte(r.text(0, len(doc))), nil
+}
+
+// reshaper rewrites the text of an encoded document. Its edits replace ranges of the source;
+// an edit that contains others is built from their text and replaces them.
+type reshaper struct {
+	s     encodeSettings
+	src   *yamlSource
+	edits []textEdit // sorted by start, not overlapping
+}
+
+// textEdit replaces the source between start and end with text
+type textEdit struct {
+	start, end int
+	text       string
+}
+
+// edit replaces the source between start and end with text, in place of the edits inside that range
+func (r *reshaper) edit(start, end int, text string) {
+	edits := r.edits[:0]
+	for _, e := range r.edits {
+		if e.start < start || e.end > end {
+			edits = append(edits, e)
+		}
+	}
+	edits = append(edits, textEdit{start: start, end: end, text: text})
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+	r.edits = edits
+}
+
+// text returns the source between start and end with the edits inside that range
+func (r *reshaper) text(start, end int) string {
+	var b strings.Builder
+	at := start
+	for _, e := range r.edits {
+		if e.start < start || e.end > end {
+			continue
+		}
+		b.Write(r.src.data[at:e.start])
+		b.WriteString(e.text)
+		at = e.end
+	}
+	b.Write(r.src.data[at:end])
+	return b.String()
+}
+
+// textRange is the text of a mapping entry or sequence item, with its head comments
+type textRange struct {
+	start, end int
+}
+
+// elementRanges returns the text of each element of c, a collection that ends before end if it is in block style.
+// It returns nil if the text of a flow collection cannot be found.
+func (r *reshaper) elementRanges(c *yamlCollection, end int) []textRange {
+	ranges := make([]textRange, len(c.elements))
+	if c.flow {
+		for i, e := range c.elements {
+			_, valueEnd, ok := r.src.valueRange(e.value, true)
+			if !ok {
+				return nil
+			}
+			ranges[i] = textRange{start: e.start, end: valueEnd}
+		}
+		return ranges
+	}
+	for i, e := range c.elements {
+		ranges[i].start = r.src.headCommentsStart(e.start)
+		if i > 0 {
+			ranges[i-1].end = r.src.lines[r.src.line(ranges[i].start)] - 1
+		}
+	}
+	if last := len(ranges) - 1; last >= 0 {
+		ranges[last].end = r.src.trimEnd(ranges[last].start, end, r.src.column(c.elements[last].start))
+	}
+	return ranges
+}
+
+// reshapeNode reorders the keys of the mappings in node and restyles its small collections.
+// rv is the value encoded as node, or invalid if unknown. A node in block style ends before end.
+func (r *reshaper) reshapeNode(node ast.Node, rv reflect.Value, end int) {
+	switch n := node.(type) {
+	case *ast.MappingNode:
+		r.reshapeMapping(n, n.Values, rv, end)
+	case *ast.MappingValueNode:
+		r.reshapeMapping(n, []*ast.MappingValueNode{n}, rv, end)
+	case *ast.SequenceNode:
+		c, _ := r.src.collection(n)
+		ranges := r.elementRanges(c, end)
+		elems := encodedValue(rv)
+		if elems.IsValid() && elems.Kind() != reflect.Slice && elems.Kind() != reflect.Array {
+			elems = reflect.Value{}
+		}
+		for i, v := range n.Values {
+			var elem reflect.Value
+			if elems.IsValid() && i < elems.Len() {
+				elem = elems.Index(i)
+			}
+			if ranges == nil {
+				r.reshapeNode(v, elem, 0)
+				continue
+			}
+			r.reshapeNode(v, elem, ranges[i].end)
+			if !c.flow && r.s.flowable(v) {
+				if vc, ok := r.src.collection(v); ok {
+					r.edit(vc.elements[0].start, ranges[i].end, r.flowText(v))
+				}
+			}
+		}
+	case *ast.AnchorNode:
+		r.reshapeNode(n.Value, rv, end)
+	case *ast.TagNode:
+		r.reshapeNode(n.Value, rv, end)
+	}
+}
+
+func (r *reshaper) reshapeMapping(node ast.Node, values []*ast.MappingValueNode, rv reflect.Value, end int) {
+	c, _ := r.src.collection(node)
+	ranges := r.elementRanges(c, end)
+	isStruct, child := mappingOrigin(rv)
+	for i, mv := range values {
+		if ranges == nil {
+			r.reshapeNode(mv.Value, child(mappingKey(mv.Key)), 0)
+			continue
+		}
+		r.reshapeNode(mv.Value, child(mappingKey(mv.Key)), ranges[i].end)
+		if !c.flow && r.s.flowable(mv.Value) {
+			r.edit(c.elements[i].indicator, ranges[i].end, " "+r.flowText(mv.Value))
+		}
+	}
+	o := r.s.keyOrder
+	if ranges == nil || o == nil || (isStruct && !o.structs) || (len(o.priority) == 0 && o.compare == nil) {
+		return
+	}
+	rank := func(mv *ast.MappingValueNode) int {
+		key := mappingKey(mv.Key)
+		for i, k := range o.priority {
+			if k == key {
+				return i
+			}
+		}
+		return len(o.priority)
+	}
+	less := func(a, b *ast.MappingValueNode) bool {
+		ra, rb := rank(a), rank(b)
+		if ra != rb {
+			return ra < rb
+		}
+		return o.compare != nil && o.compare(mappingKey(a.Key), mappingKey(b.Key)) < 0
+	}
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return less(values[order[i]], values[order[j]]) })
+	if sort.IntsAreSorted(order) {
+		return
+	}
+
+	// The entries move between the separators of their positions, so the first may follow a "-" or "{"
+	var b strings.Builder
+	for i, j := range order {
+		if i > 0 {
+			b.Write(r.src.data[ranges[i-1].end:ranges[i].start])
+		}
+		b.WriteString(r.text(ranges[j].start, ranges[j].end))
+	}
+	r.edit(ranges[0].start, ranges[len(ranges)-1].end, b.String())
+	// The nodes are in output order for flowText
+	sorted := make([]*ast.MappingValueNode, len(values))
+	for i, j := range order {
+		sorted[i] = values[j]
+	}
+	copy(values, sorted)
+}
+
+// flowText returns the flow style text of a flowable collection, from the text of its scalars
+func (r *reshaper) flowText(node ast.Node) string {
+	var items []string
+	switch n := node.(type) {
+	case *ast.SequenceNode:
+		for _, v := range n.Values {
+			items = append(items, r.scalarText(v))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case *ast.MappingNode:
+		for _, mv := range n.Values {
+			key := mv.Key.String()
+			if _, ok := mv.Key.(*ast.StringNode); ok {
+				key = strings.TrimRight(string(r.src.data[r.src.offset(mv.Key.GetToken()):r.src.offset(mv.Start)]), " ")
+			}
+			items = append(items, key+": "+r.scalarText(mv.Value))
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	}
+	return node.String()
+}
+
+// scalarText returns the text of a single-line scalar
+func (r *reshaper) scalarText(node ast.Node) string {
+	start, end, ok := r.src.valueRange(node, false)
+	if !ok {
+		return node.String()
+	}
+	return string(r.src.data[start:end])
+}
+
+// flowable reports whether a collection can be written in flow style by flowSmallCollections:
+// it is a non-empty block collection of at most flowMaxItems single-line scalars, without comments
+func (s encodeSettings) flowable(node ast.Node) bool {
+	var items []ast.Node
+	switch n := node.(type) {
+	case *ast.SequenceNode:
+		if n.IsFlowStyle || len(n.Values) > s.flowMaxItems || n.GetComment() != nil {
+			return false
+		}
+		for _, c := range n.ValueHeadComments {
+			if c != nil {
+				return false
+			}
+		}
+		items = n.Values
+	case *ast.MappingNode:
+		if n.IsFlowStyle || len(n.Values) > s.flowMaxItems || n.GetComment() != nil {
+			return false
+		}
+		for _, mv := range n.Values {
+			if mv.GetComment() != nil {
+				return false
+			}
+			items = append(items, mv.Key, mv.Value)
+		}
+	default:
+		return false
+	}
+	if len(items) == 0 {
+		return false
+	}
+	for _, item := range items {
+		if !flowScalar(item) || item.GetComment() != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// flowScalar reports whether node is a single-line scalar that can be written unchanged in flow context
+func flowScalar(node ast.Node) bool {
+	switch n := node.(type) {
+	case *ast.MappingKeyNode:
+		return flowScalar(n.Value)
+	case *ast.StringNode:
+		if strings.Contains(n.String(), "\n") {
+			return false
+		}
+		if n.Token.Type == token.DoubleQuoteType || n.Token.Type == token.SingleQuoteType {
+			return true
+		}
+		// Plain scalars must not contain flow indicators
+		return !strings.ContainsAny(n.Value, ",[]{}#:")
+	case *ast.IntegerNode, *ast.FloatNode, *ast.BoolNode, *ast.NullNode, *ast.InfinityNode, *ast.NanNode:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,554 @@
+package yamlformat
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// SequenceDecoder decodes the elements of a sequence one at a time, so huge inputs are not held in memory.
+// Use it like bufio.Scanner:
+//
+//	dec := yamlformat.NewSequenceDecoder(r, yamlformat.FormatJSON, ".items")
+//	for dec.Next() {
+//		var item Item
+//		if err := dec.Decode(&item); err != nil {
+//			return err
+//		}
+//	}
+//	if err := dec.Err(); err != nil {
+//		return err
+//	}
+type SequenceDecoder struct {
+	next   func() ([]byte, error) // returns io.EOF after the last element
+	decode func(data []byte, v interface{}) error
+	elem   []byte
+	index  int
+	err    error
+}
+
+// NewSequenceDecoder creates a SequenceDecoder for the sequence at path in the input, which is read in format.
+// path is "" (or ".") for a top-level sequence, or a dotted list of mapping keys such as ".items" or ".spec.items".
+// Keys may be quoted as in Document paths, like ."a.b"; indexes are not supported.
+// A null or missing value at the end of the path has no elements.
+//
+// Memory is bounded by the largest element for JSON, for JSON Lines (where each record is an element
+// and path must be empty) and for YAML in block style, where the elements that define anchors are kept too
+// for the aliases of later elements. Other YAML (flow style, anchors on the path, ...)
+// and registered formats are read as a whole first.
+func NewSequenceDecoder(r io.Reader, format Format, path string, opts ...yaml.DecodeOption) *SequenceDecoder {
+	d := &SequenceDecoder{index: -1}
+	keys, err := sequencePathKeys(path)
+	if err == nil {
+		err = format.Validate()
+	}
+	if err != nil {
+		d.err = err
+		return d
+	}
+
+	switch format {
+	case FormatJSON, FormatJSONPretty:
+		dec := json.NewDecoder(r)
+		s := &jsonSequenceScanner{dec: dec, keys: keys, path: path}
+		d.next = s.next
+		d.decode = func(data []byte, v interface{}) error { return UnmarshalJSON(data, v, opts...) }
+	case FormatJSONL:
+		if len(keys) > 0 {
+			d.err = fmt.Errorf("path %s is not supported for %s: each record is an element", path, format)
+			return d
+		}
+		d.next = nextJSONLineFunc(r)
+		d.decode = func(data []byte, v interface{}) error { return UnmarshalJSON(data, v, opts...) }
+	case FormatYAML:
+		s := &yamlSequenceScanner{r: bufio.NewReader(r), keys: keys, path: path, unmarshal: yaml.Unmarshal}
+		d.next = s.next
+		d.decode = func(data []byte, v interface{}) error { return decodeSequenceItem(data, v, opts) }
+	default:
+		unmarshal := func(data []byte, v interface{}) error { return format.Unmarshal(data, v, opts...) }
+		s := &yamlSequenceScanner{r: bufio.NewReader(r), keys: keys, path: path, unmarshal: unmarshal}
+		d.next = func() ([]byte, error) {
+			if !s.fallback {
+				if err := s.readAll(); err != nil {
+					return nil, err
+				}
+			}
+			return s.next()
+		}
+		d.decode = func(data []byte, v interface{}) error { return decodeSequenceItem(data, v, opts) }
+	}
+	return d
+}
+
+// NewSequenceDecoder is like NewSequenceDecoder but uses o instead of the package defaults
+func (o Options) NewSequenceDecoder(r io.Reader, format Format, path string, opts ...yaml.DecodeOption) *SequenceDecoder {
+	return NewSequenceDecoder(r, format, path, o.decodeOptions(opts)...)
+}
+
+// Next advances to the next element. It returns false after the last element or on an error.
+func (d *SequenceDecoder) Next() bool {
+	if d.err != nil {
+		return false
+	}
+	elem, err := d.next()
+	if err != nil {
+		if !errors.Is(err, io.EOF) {
+			d.err = err
+		}
+		d.elem = nil
+		return false
+	}
+	d.elem = elem
+	d.index++
+	return true
+}
+
+// Decode unmarshals the current element into v
+func (d *SequenceDecoder) Decode(v interface{}) error {
+	if d.elem == nil {
+		return errors.New("no current element: call Next first")
+	}
+	if err := d.decode(d.elem, v); err != nil {
+		return fmt.Errorf("element %d: %w", d.index, err)
+	}
+	return nil
+}
+
+// Index returns the zero-based index of the current element
+func (d *SequenceDecoder) Index() int {
+	return d.index
+}
+
+// Err returns the first error other than the end of the sequence
+func (d *SequenceDecoder) Err() error {
+	return d.err
+}
+
+// sequencePathKeys returns the mapping keys of path, which uses the syntax of Document paths without indexes
+func sequencePathKeys(path string) ([]string, error) {
+	steps, err := parseDocumentPath(path)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(steps))
+	for _, step := range steps {
+		if step.isIndex {
+			return nil, fmt.Errorf("invalid path %q: indexes are not supported", path)
+		}
+		keys = append(keys, step.key)
+	}
+	return keys, nil
+}
+
+// jsonSequenceScanner reads the elements of a JSON array token by token
+type jsonSequenceScanner struct {
+	dec     *json.Decoder
+	keys    []string
+	path    string
+	started bool
+	done    bool
+}
+
+func (s *jsonSequenceScanner) next() ([]byte, error) {
+	if !s.started {
+		s.started = true
+		if err := s.locate(); err != nil {
+			return nil, err
+		}
+	}
+	if s.done || !s.dec.More() {
+		return nil, io.EOF
+	}
+	var raw json.RawMessage
+	if err := s.dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	return raw, nil
+}
+
+// locate reads up to the opening bracket of the array at the path, skipping other values
+func (s *jsonSequenceScanner) locate() error {
+	for _, key := range s.keys {
+		tok, err := s.dec.Token()
+		if err != nil {
+			return fmt.Errorf("invalid JSON: %w", err)
+		}
+		if tok == nil {
+			s.done = true
+			return nil
+		}
+		if tok != json.Delim('{') {
+			return fmt.Errorf("path %s not found: %v is not an object", s.path, tok)
+		}
+		found := false
+		for s.dec.More() {
+			tok, err := s.dec.Token()
+			if err != nil {
+				return fmt.Errorf("invalid JSON: %w", err)
+			}
+			if tok == key {
+				found = true
+				break
+			}
+			if err := skipJSONValue(s.dec); err != nil {
+				return err
+			}
+		}
+		if !found {
+			s.done = true
+			return nil
+		}
+	}
+	tok, err := s.dec.Token()
+	if err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	switch tok {
+	case json.Delim('['):
+		return nil
+	case nil:
+		s.done = true
+		return nil
+	default:
+		return fmt.Errorf("value at path %s is not an array", s.pathName())
+	}
+}
+
+func (s *jsonSequenceScanner) pathName() string {
+	if s.path == "" {
+		return "."
+	}
+	return s.path
+}
+
+// skipJSONValue reads the next value from dec without keeping it in memory
+func skipJSONValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return fmt.Errorf("invalid JSON: %w", err)
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// yamlSequenceScanner reads the items of a block sequence line by line.
+// Lines are only kept while looking for the sequence, so the input can be read as a whole
+// if it is not in block style. Each item is returned as a sequence of that single item.
+type yamlSequenceScanner struct {
+	r         *bufio.Reader
+	keys      []string
+	path      string
+	unmarshal func(data []byte, v interface{}) error // for the fallback
+
+	seen    []byte // lines read before the sequence was found
+	started bool
+	indent  int    // indentation of the "-" of the items
+	pending string // first line of the next item
+	done    bool
+	anchors []byte // the items that define anchors, for the aliases of later items
+
+	fallback bool
+	items    []rawYAML
+}
+
+// rawYAML keeps the source of a value, with aliases resolved by goccy/go-yaml
+type rawYAML []byte
+
+func (r *rawYAML) UnmarshalYAML(b []byte) error {
+	*r = append((*r)[:0], b...)
+	return nil
+}
+
+func (s *yamlSequenceScanner) next() ([]byte, error) {
+	if s.fallback {
+		if len(s.items) == 0 {
+			return nil, io.EOF
+		}
+		item := s.items[0]
+		s.items = s.items[1:]
+		return sequenceItem(item), nil
+	}
+	if !s.started {
+		s.started = true
+		found, err := s.locate()
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			if err := s.readAll(); err != nil {
+				return nil, err
+			}
+			return s.next()
+		}
+	}
+	return s.nextItem()
+}
+
+// locate reads up to the first item of the block sequence at the path.
+// It returns false if the input is not simple enough to stream, e.g. in flow style.
+func (s *yamlSequenceScanner) locate() (bool, error) {
+	level, parentIndent, keyIndent := 0, -1, -1
+	inDocument := false
+	for {
+		line, err := s.r.ReadString('\n')
+		s.seen = append(s.seen, line...)
+		if line == "" && err != nil {
+			if errors.Is(err, io.EOF) {
+				return false, nil
+			}
+			return false, err
+		}
+		if isBlankOrCommentLine(line) {
+			continue
+		}
+		if !inDocument && strings.HasPrefix(line, "%") {
+			continue
+		}
+		if isDocumentMarker(line, "---") || isDocumentMarker(line, "...") {
+			if inDocument || markerHasContent(line) {
+				// Another document, or content on the marker line
+				return false, nil
+			}
+			inDocument = true
+			continue
+		}
+		inDocument = true
+		if hasAnchor(line) {
+			return false, nil
+		}
+
+		ind := len(line) - len(strings.TrimLeft(line, " "))
+		content := line[ind:]
+		if level == len(s.keys) {
+			if isSequenceItem(content) && (ind > parentIndent || (level > 0 && ind == parentIndent)) {
+				s.indent, s.pending, s.seen = ind, line, nil
+				return true, nil
+			}
+			return false, nil
+		}
+		if keyIndent == -1 {
+			if ind <= parentIndent {
+				return false, nil
+			}
+			keyIndent = ind
+		}
+		if ind < keyIndent {
+			return false, nil
+		}
+		if ind > keyIndent {
+			continue
+		}
+		key, rest, ok := splitMappingLine(content)
+		if !ok {
+			return false, nil
+		}
+		if key != s.keys[level] {
+			continue
+		}
+		if trimmed := strings.TrimSpace(rest); trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			// A value on the same line: a flow sequence, an anchor, a tag, ...
+			return false, nil
+		}
+		level, parentIndent, keyIndent = level+1, ind, -1
+	}
+}
+
+// nextItem reads the lines of the next item and returns them as a YAML document
+func (s *yamlSequenceScanner) nextItem() ([]byte, error) {
+	if s.pending == "" {
+		return nil, io.EOF
+	}
+	lines := []string{s.pending}
+	s.pending = ""
+	for !s.done {
+		line, err := s.r.ReadString('\n')
+		if line == "" && err != nil {
+			if !errors.Is(err, io.EOF) {
+				return nil, err
+			}
+			s.done = true
+			break
+		}
+		ind := len(line) - len(strings.TrimLeft(line, " "))
+		switch {
+		case isBlankOrCommentLine(line) || ind > s.indent:
+			lines = append(lines, line)
+			continue
+		case ind == s.indent && isSequenceItem(line[ind:]):
+			s.pending = line
+		default:
+			// The end of the sequence; the rest of the input is not needed
+			s.done = true
+		}
+		break
+	}
+	item := sequenceItemSource(lines, s.indent)
+	data := item
+	if len(s.anchors) > 0 && slices.ContainsFunc(lines, hasAlias) {
+		data = anchoredItem(s.anchors, item)
+	}
+	if slices.ContainsFunc(lines, hasAnchor) {
+		s.anchors = append(s.anchors, item...)
+	}
+	return data, nil
+}
+
+// anchoredItemStart starts the source of an item returned with the items whose anchors it may refer to
+const anchoredItemStart = "anchors:\n"
+
+// anchoredItem returns the source of item with the items that define anchors before it, as a mapping
+// of both sequences that decodeSequenceItem reads
+func anchoredItem(anchors, item []byte) []byte {
+	data := append([]byte(anchoredItemStart), anchors...)
+	data = append(data, "item:\n"...)
+	return append(data, item...)
+}
+
+// readAll reads the whole input and the items of the sequence at the path
+func (s *yamlSequenceScanner) readAll() error {
+	rest, err := io.ReadAll(s.r)
+	if err != nil {
+		return err
+	}
+	data := append(s.seen, rest...)
+	s.seen, s.fallback = nil, true
+	for _, key := range s.keys {
+		var m map[string]rawYAML
+		if err := s.unmarshal(data, &m); err != nil {
+			return fmt.Errorf("path %s not found: %w", s.path, err)
+		}
+		value, ok := m[key]
+		if !ok {
+			return nil
+		}
+		data = value
+	}
+	if err := s.unmarshal(data, &s.items); err != nil {
+		return fmt.Errorf("value at path %s is not a sequence: %w", s.path, err)
+	}
+	return nil
+}
+
+// sequenceItemSource turns the lines of a block sequence item into a sequence of that single item
+// by removing the indentation of the sequence
+func sequenceItemSource(lines []string, indent int) []byte {
+	var buf bytes.Buffer
+	for _, line := range lines {
+		n := len(line) - len(strings.TrimLeft(line, " "))
+		if n > indent {
+			n = indent
+		}
+		buf.WriteString(line[n:])
+	}
+	return buf.Bytes()
+}
+
+// decodeSequenceItem unmarshals a sequence of a single item into v, which must be a non-nil pointer.
+// data may also be an item with the items whose anchors it refers to, from anchoredItem.
+func decodeSequenceItem(data []byte, v interface{}, opts []yaml.DecodeOption) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("decode into non-pointer or nil %T", v)
+	}
+	items := reflect.New(reflect.SliceOf(rv.Type().Elem()))
+	target := items
+	if bytes.HasPrefix(data, []byte(anchoredItemStart)) {
+		// The other items are decoded as interface{}, which defines their anchors
+		target = reflect.New(reflect.StructOf([]reflect.StructField{
+			{Name: "Anchors", Type: reflect.TypeOf((*interface{})(nil)).Elem(), Tag: `yaml:"anchors"`},
+			{Name: "Item", Type: items.Type().Elem(), Tag: `yaml:"item"`},
+		}))
+		items = target.Elem().Field(1).Addr()
+	}
+	if err := Unmarshal(data, target.Interface(), opts...); err != nil {
+		return err
+	}
+	if n := items.Elem().Len(); n != 1 {
+		return fmt.Errorf("expected a single element, got %d", n)
+	}
+	rv.Elem().Set(items.Elem().Index(0))
+	return nil
+}
+
+// hasAnchor reports whether a line may define an anchor, which later elements could refer to
+func hasAnchor(line string) bool {
+	return hasIndicator(line, '&')
+}
+
+// hasAlias reports whether a line may refer to an anchor
+func hasAlias(line string) bool {
+	return hasIndicator(line, '*')
+}
+
+// hasIndicator reports whether a line may have the anchor or alias indicator c before a node
+func hasIndicator(line string, c byte) bool {
+	for i := strings.IndexByte(line, c); i >= 0; {
+		if i == 0 || strings.ContainsAny(line[i-1:i], " \t[{,:-") {
+			return true
+		}
+		next := strings.IndexByte(line[i+1:], c)
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return false
+}
+
+// isSequenceItem reports whether content starts with a block sequence indicator
+func isSequenceItem(content string) bool {
+	return content == "-" || (strings.HasPrefix(content, "-") && len(content) > 1 && strings.ContainsAny(content[1:2], " \t\r\n"))
+}
+
+// isBlankOrCommentLine reports whether line has no content
+func isBlankOrCommentLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed == "" || strings.HasPrefix(trimmed, "#")
+}
+
+// splitMappingLine splits a line of a block mapping into its key and the rest after the colon.
+// It returns false for anything else, e.g. flow collections or complex keys.
+func splitMappingLine(content string) (key, rest string, ok bool) {
+	if content == "" || strings.ContainsAny(content[:1], "-[{?&*!|>") {
+		return "", "", false
+	}
+	if q := content[0]; q == '"' || q == '\'' {
+		end := strings.IndexByte(content[1:], q)
+		if end < 0 {
+			return "", "", false
+		}
+		key, rest = content[1:end+1], content[end+2:]
+		if q == '"' && strings.Contains(key, "\\") {
+			// An escape sequence: the key is compared as written, so leave it to the fallback
+			return "", "", false
+		}
+		if !strings.HasPrefix(rest, ":") || (len(rest) > 1 && !strings.ContainsAny(rest[1:2], " \t\r\n")) {
+			return "", "", false
+		}
+		return key, rest[1:], true
+	}
+	for i := 0; i < len(content); i++ {
+		if content[i] == ':' && (i+1 == len(content) || strings.ContainsAny(content[i+1:i+2], " \t\r\n")) {
+			return strings.TrimRight(content[:i], " \t"), content[i+1:], true
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,245 @@
+package yamlformat
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+	"github.com/google/go-cmp/cmp"
+)
+
+type sequenceTestItem struct {
+	Name string `yaml:"name"`
+	Size int    `yaml:"size"`
+}
+
+// decodeSequence decodes all elements of the sequence at path
+func decodeSequence(t *testing.T, input string, format Format, path string) ([]sequenceTestItem, error) {
+	t.Helper()
+	dec := NewSequenceDecoder(strings.NewReader(input), format, path)
+	var items []sequenceTestItem
+	for dec.Next() {
+		var item sequenceTestItem
+		if err := dec.Decode(&item); err != nil {
+			return nil, err
+		}
+		if dec.Index() != len(items) {
+			t.Errorf("Index() = %d, want %d", dec.Index(), len(items))
+		}
+		items = append(items, item)
+	}
+	return items, dec.Err()
+}
+
+func TestSequenceDecoder(t *testing.T) {
+	twoItems := []sequenceTestItem{{Name: "a", Size: 1}, {Name: "b", Size: 2}}
+	tests := []struct {
+		name   string
+		input  string
+		format Format
+		path   string
+		want   []sequenceTestItem
+	}{
+		{
+			name:   "JSON array",
+			input:  `[{"name": "a", "size": 1}, {"name": "b", "size": 2}]`,
+			format: FormatJSON,
+			want:   twoItems,
+		},
+		{
+			name:   "JSON path",
+			input:  `{"kind": "List", "meta": {"items": [0], "x": {}}, "items": [{"name": "a", "size": 1}, {"name": "b", "size": 2}], "after": 1}`,
+			format: FormatJSON,
+			path:   ".items",
+			want:   twoItems,
+		},
+		{
+			name:   "nested JSON path",
+			input:  "{\n  \"spec\": {\n    \"items\": [\n      {\"name\": \"a\", \"size\": 1}\n    ]\n  }\n}\n",
+			format: FormatJSONPretty,
+			path:   "$.spec.items",
+			want:   twoItems[:1],
+		},
+		{
+			name:   "JSON null",
+			input:  `{"items": null}`,
+			format: FormatJSON,
+			path:   ".items",
+		},
+		{
+			name:   "JSON missing key",
+			input:  `{"other": [1]}`,
+			format: FormatJSON,
+			path:   ".items",
+		},
+		{
+			name:   "JSON Lines",
+			input:  "{\"name\": \"a\", \"size\": 1}\n\n{\"name\": \"b\", \"size\": 2}",
+			format: FormatJSONL,
+			want:   twoItems,
+		},
+		{
+			name:   "YAML sequence",
+			input:  "# export\n---\n- name: a\n  size: 1\n\n# second\n- name: b\n  size: 2\n",
+			format: FormatYAML,
+			want:   twoItems,
+		},
+		{
+			name:   "YAML path",
+			input:  "kind: List\nmetadata:\n  items: 0\nitems:\n- name: a\n  size: 1\n-   name: b\n    size: 2\nafter: [1\n",
+			format: FormatYAML,
+			path:   ".items",
+			want:   twoItems,
+		},
+		{
+			name:   "nested YAML path",
+			input:  "spec:\n  \"items\": # the items\n    -\n      name: a\n      size: 1\n    - {name: b, size: 2}\n",
+			format: FormatYAML,
+			path:   ".spec.items",
+			want:   twoItems,
+		},
+		{
+			name:   "YAML flow sequence",
+			input:  "items: [{name: a, size: 1}, {name: b, size: 2}]\n",
+			format: FormatYAML,
+			path:   ".items",
+			want:   twoItems,
+		},
+		{
+			name:   "YAML anchors",
+			input:  "a: &a {name: a, size: 1}\nb: &b\n  name: b\n  size: 2\nitems:\n- *a\n- *b\n",
+			format: FormatYAML,
+			path:   ".items",
+			want:   twoItems,
+		},
+		{
+			name:   "YAML aliases between items",
+			input:  "items:\n- name: a\n  size: 1\n- &b\n  name: b\n  size: 2\n- *b\n- &b {name: a, size: 1}\n- *b\n",
+			format: FormatYAML,
+			path:   ".items",
+			want:   []sequenceTestItem{twoItems[0], twoItems[1], twoItems[1], twoItems[0], twoItems[0]},
+		},
+		{
+			name:   "JSON quoted key",
+			input:  `{"a": {"b": []}, "a.b": [{"name": "a", "size": 1}]}`,
+			format: FormatJSON,
+			path:   `."a.b"`,
+			want:   twoItems[:1],
+		},
+		{
+			name:   "YAML quoted key",
+			input:  "a:\n  b: []\n'a.b':\n- name: a\n  size: 1\n",
+			format: FormatYAML,
+			path:   `["a.b"]`,
+			want:   twoItems[:1],
+		},
+		{
+			name:   "YAML escaped key",
+			input:  "\"a\\tb\":\n- name: a\n  size: 1\n",
+			format: FormatYAML,
+			path:   `."a\tb"`,
+			want:   twoItems[:1],
+		},
+		{
+			name:   "YAML missing key",
+			input:  "other:\n- name: a\n",
+			format: FormatYAML,
+			path:   ".items",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeSequence(t, tt.input, tt.format, tt.path)
+			if err != nil {
+				t.Fatalf("decode failed: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("elements mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSequenceDecoderBlockScalars(t *testing.T) {
+	input := "items:\n- |\n  line 1\n\n  line 2\n- - x\n  - y\n-\n- plain\n"
+	dec := NewSequenceDecoder(strings.NewReader(input), FormatYAML, ".items")
+	var got []interface{}
+	for dec.Next() {
+		var v interface{}
+		if err := dec.Decode(&v); err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		got = append(got, v)
+	}
+	if err := dec.Err(); err != nil {
+		t.Fatalf("Err() = %v", err)
+	}
+	want := []interface{}{"line 1\n\nline 2\n", []interface{}{"x", "y"}, nil, "plain"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("elements mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSequenceDecoderRegisteredFormatOptions(t *testing.T) {
+	withTestRegistry(t)
+	var policies []NumberPolicy
+	spec := flowYAMLSpec
+	spec.Unmarshal = func(data []byte, v interface{}, opts ...yaml.DecodeOption) error {
+		policies = append(policies, loadDecodeSettings(opts).numbers)
+		return Unmarshal(data, v, opts...)
+	}
+	if err := RegisterFormat(spec); err != nil {
+		t.Fatalf("RegisterFormat failed: %v", err)
+	}
+
+	dec := NewSequenceDecoder(strings.NewReader("{items: [1, 2]}"), spec.Name, ".items", DecodeNumbers(NumberJSONNumber))
+	var got []interface{}
+	for dec.Next() {
+		var v interface{}
+		if err := dec.Decode(&v); err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		got = append(got, v)
+	}
+	if err := dec.Err(); err != nil {
+		t.Fatalf("Err() = %v", err)
+	}
+	if diff := cmp.Diff([]interface{}{json.Number("1"), json.Number("2")}, got); diff != "" {
+		t.Errorf("elements mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]NumberPolicy{NumberJSONNumber, NumberJSONNumber}, policies); diff != "" {
+		t.Errorf("policies given to Unmarshal mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSequenceDecoderErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		format Format
+		path   string
+		want   string
+	}{
+		{name: "invalid path", format: FormatJSON, path: "items", want: `invalid path "items": must start with "."`},
+		{name: "empty key", format: FormatJSON, path: ".a..b", want: `invalid path ".a..b": empty key`},
+		{name: "index", format: FormatYAML, path: ".items[0]", want: `invalid path ".items[0]": indexes are not supported`},
+		{name: "unknown format", format: "xml", want: "invalid format: xml"},
+		{name: "JSON Lines path", format: FormatJSONL, path: ".items", want: "path .items is not supported for jsonl: each record is an element"},
+		{name: "JSON object", input: `{"a": 1}`, format: FormatJSON, want: "value at path . is not an array"},
+		{name: "JSON not an array", input: `{"items": {}}`, format: FormatJSON, path: ".items", want: "value at path .items is not an array"},
+		{name: "invalid JSON element", input: `[{"name": "a"}, {"name": ]`, format: FormatJSON, want: "invalid JSON"},
+		{name: "YAML not a sequence", input: "items:\n  a: 1\n", format: FormatYAML, path: ".items", want: "value at path .items is not a sequence"},
+		{name: "element type", input: "- name: [a]\n", format: FormatYAML, want: "element 0: "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeSequence(t, tt.input, tt.format, tt.path)
+			if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("error = %v, want prefix %q", err, tt.want)
+			}
+		})
+	}
+}
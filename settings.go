@@ -0,0 +1,153 @@
+package yamlformat
+
+import "github.com/goccy/go-yaml"
+
+// Package-specific settings travel inside yaml.EncodeOption and yaml.DecodeOption values, so they can be
+// passed anywhere the package accepts options and mixed freely with goccy/go-yaml options.
+// Each setting is stored as a custom marshaler (or unmarshaler) for a private probe type;
+// loadEncodeSettings and loadDecodeSettings encode (or decode) the probes to read the settings back.
+// As with other options, a later option overrides an earlier one.
+
+// encodeSettings holds package-specific encoding settings
+type encodeSettings struct {
+	nonFinite    NonFiniteFloatPolicy
+	floatFormat  FloatFormat
+	int64Strings Int64StringPolicy
+	without      []Default
+	keyOrder     *KeyOrder
+	flowMaxItems int
+	jsonLayout   jsonLayout
+	// documentStart writes "---" before the first YAML document too
+	documentStart bool
+	comments      yaml.CommentMap // by goccy/go-yaml path
+	header        []string
+	footer        []string
+	jsonc         bool
+}
+
+// nonFiniteProbe reads the setting of JSONNonFiniteFloats
+type nonFiniteProbe struct{ s *encodeSettings }
+
+// floatFormatProbe reads the setting of FloatFormatter
+type floatFormatProbe struct{ s *encodeSettings }
+
+// int64StringsProbe reads the setting of JSONInt64Strings
+type int64StringsProbe struct{ s *encodeSettings }
+
+// withoutEncodeProbe reads the defaults removed by Options.Without
+type withoutEncodeProbe struct{ s *encodeSettings }
+
+// keyOrderProbe reads the setting of OrderKeys
+type keyOrderProbe struct{ s *encodeSettings }
+
+// flowProbe reads the setting of flowSmallCollections
+type flowProbe struct{ s *encodeSettings }
+
+// jsonLayoutProbe reads the setting of jsonIndent
+type jsonLayoutProbe struct{ s *encodeSettings }
+
+// documentStartProbe reads the setting of ExplicitDocumentStart
+type documentStartProbe struct{ s *encodeSettings }
+
+// commentsProbe reads the setting of Comments
+type commentsProbe struct{ s *encodeSettings }
+
+// headerProbe reads the setting of HeaderComment
+type headerProbe struct{ s *encodeSettings }
+
+// footerProbe reads the setting of FooterComment
+type footerProbe struct{ s *encodeSettings }
+
+// jsoncProbe reads the setting of JSONC
+type jsoncProbe struct{ s *encodeSettings }
+
+// loadEncodeSettings reads the package-specific settings from opts.
+// Errors from opts are ignored here; they surface when opts are used for encoding.
+func loadEncodeSettings(opts []yaml.EncodeOption) encodeSettings {
+	var s encodeSettings
+	_, _ = yaml.ValueToNode([]interface{}{
+		nonFiniteProbe{&s},
+		floatFormatProbe{&s},
+		int64StringsProbe{&s},
+		withoutEncodeProbe{&s},
+		keyOrderProbe{&s},
+		flowProbe{&s},
+		jsonLayoutProbe{&s},
+		documentStartProbe{&s},
+		commentsProbe{&s},
+		headerProbe{&s},
+		footerProbe{&s},
+		jsoncProbe{&s},
+	}, opts...)
+	return s
+}
+
+// settingOption returns an option that applies set to the settings when probe type P is encoded
+func settingOption[P any](set func(P)) yaml.EncodeOption {
+	return yaml.CustomMarshaler[P](func(p P) ([]byte, error) {
+		set(p)
+		return []byte("null"), nil
+	})
+}
+
+// decodeSettings holds package-specific decoding settings
+type decodeSettings struct {
+	numbers NumberPolicy
+	without []Default
+	// ordered decodes mappings in interface{} values as yaml.MapSlice in source order
+	ordered bool
+	// orderedMaps decodes mappings in interface{} values as *OrderedMap
+	orderedMaps bool
+	// json decodes JSON input: repeated mapping keys take the last value, and number literals
+	// that goccy/go-yaml reads as strings, such as 1e3, are numbers in interface{} values
+	json bool
+	// useOrderedMap is set by yaml.UseOrderedMap
+	useOrderedMap bool
+}
+
+// numbersProbe reads the setting of DecodeNumbers
+type numbersProbe struct{ s *decodeSettings }
+
+// withoutDecodeProbe reads the defaults removed by Options.Without
+type withoutDecodeProbe struct{ s *decodeSettings }
+
+// orderedMapsProbe reads the setting of DecodeOrderedMaps
+type orderedMapsProbe struct{ s *decodeSettings }
+
+// jsonProbe reads the setting of jsonDecodeOptions
+type jsonProbe struct{ s *decodeSettings }
+
+// decodeSettingsProbes is decoded from decodeSettingsProbeSource to read the settings
+type decodeSettingsProbes struct {
+	Numbers     numbersProbe       `yaml:"numbers"`
+	Without     withoutDecodeProbe `yaml:"without"`
+	OrderedMaps orderedMapsProbe   `yaml:"orderedMaps"`
+	JSON        jsonProbe          `yaml:"json"`
+	// Mapping is decoded by goccy/go-yaml, as yaml.MapSlice with yaml.UseOrderedMap
+	Mapping interface{} `yaml:"mapping"`
+}
+
+const decodeSettingsProbeSource = "{numbers: 0, without: 0, orderedMaps: 0, json: 0, mapping: {}}"
+
+// loadDecodeSettings reads the package-specific settings from opts.
+// Errors from opts are ignored here; they surface when opts are used for decoding.
+func loadDecodeSettings(opts []yaml.DecodeOption) decodeSettings {
+	var s decodeSettings
+	probes := decodeSettingsProbes{
+		Numbers:     numbersProbe{&s},
+		Without:     withoutDecodeProbe{&s},
+		OrderedMaps: orderedMapsProbe{&s},
+		JSON:        jsonProbe{&s},
+	}
+	_ = yaml.UnmarshalWithOptions([]byte(decodeSettingsProbeSource), &probes, opts...)
+	_, s.useOrderedMap = probes.Mapping.(yaml.MapSlice)
+	return s
+}
+
+// decodeSettingOption returns an option that applies set to the settings when probe type P is decoded
+func decodeSettingOption[P any](set func(P)) yaml.DecodeOption {
+	return yaml.CustomUnmarshaler[P](func(p *P, _ []byte) error {
+		set(*p)
+		return nil
+	})
+}
@@ -88,6 +88,8 @@ func TestMarshalWithExplicitTypes(t *testing.T) {
 		"uint16_val: 65535",
 		"uint32_val: 4294967295",
 		"uint64_val: 18446744073709551615",
+		"float32_val: 3.14",
+		"float64_val: 3.141592653589793",
 	}
 	
 	for _, expected := range expectedValues {
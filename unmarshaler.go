@@ -0,0 +1,127 @@
+package yamlformat
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"unsafe"
+
+	"github.com/goccy/go-yaml"
+)
+
+// AllowIntegerStrings accepts quoted decimal strings such as "9007199254740993" in integer fields,
+// in addition to numbers. It decodes the output of JSONInt64Strings.
+func AllowIntegerStrings() yaml.DecodeOption {
+	return combineDecodeOptions(
+		signedIntegerUnmarshaler[int](),
+		signedIntegerUnmarshaler[int8](),
+		signedIntegerUnmarshaler[int16](),
+		signedIntegerUnmarshaler[int32](),
+		signedIntegerUnmarshaler[int64](),
+		unsignedIntegerUnmarshaler[uint](),
+		unsignedIntegerUnmarshaler[uint8](),
+		unsignedIntegerUnmarshaler[uint16](),
+		unsignedIntegerUnmarshaler[uint32](),
+		unsignedIntegerUnmarshaler[uint64](),
+	)
+}
+
+func signedIntegerUnmarshaler[T int | int8 | int16 | int32 | int64]() yaml.DecodeOption {
+	return yaml.CustomUnmarshaler[T](func(v *T, b []byte) error {
+		s, ok := unquoteScalar(b)
+		if !ok {
+			// Numbers keep the default decoding
+			return yaml.Unmarshal(b, v)
+		}
+		n, err := strconv.ParseInt(s, 10, int(unsafe.Sizeof(*v))*8)
+		if err != nil {
+			return fmt.Errorf("cannot decode string %q into %T: %w", s, *v, err)
+		}
+		*v = T(n)
+		return nil
+	})
+}
+
+func unsignedIntegerUnmarshaler[T uint | uint8 | uint16 | uint32 | uint64]() yaml.DecodeOption {
+	return yaml.CustomUnmarshaler[T](func(v *T, b []byte) error {
+		s, ok := unquoteScalar(b)
+		if !ok {
+			// Numbers keep the default decoding
+			return yaml.Unmarshal(b, v)
+		}
+		n, err := strconv.ParseUint(s, 10, int(unsafe.Sizeof(*v))*8)
+		if err != nil {
+			return fmt.Errorf("cannot decode string %q into %T: %w", s, *v, err)
+		}
+		*v = T(n)
+		return nil
+	})
+}
+
+// unquoteScalar returns the content of a single or double quoted scalar
+func unquoteScalar(b []byte) (string, bool) {
+	if len(b) < 2 || b[0] != b[len(b)-1] {
+		return "", false
+	}
+	switch b[0] {
+	case '"':
+		s, err := strconv.Unquote(string(b))
+		return s, err == nil
+	case '\'':
+		return string(b[1 : len(b)-1]), true
+	default:
+		return "", false
+	}
+}
+
+// bigNumberUnmarshalers returns custom unmarshalers that parse numeric literals of any size
+// into math/big numbers without going through float64. Quoted numbers are accepted too.
+func bigNumberUnmarshalers() yaml.DecodeOption {
+	return combineDecodeOptions(
+		yaml.CustomUnmarshaler[big.Int](unmarshalBigInt),
+		yaml.CustomUnmarshaler[big.Float](unmarshalBigFloat),
+		yaml.CustomUnmarshaler[big.Rat](unmarshalBigRat),
+	)
+}
+
+// bigNumberLiteral returns the numeric literal in b, removing surrounding quotes if any
+func bigNumberLiteral(b []byte) string {
+	b = bytes.TrimSpace(b)
+	if s, ok := unquoteScalar(b); ok {
+		return s
+	}
+	return string(b)
+}
+
+func unmarshalBigInt(v *big.Int, b []byte) error {
+	s := bigNumberLiteral(b)
+	if _, ok := v.SetString(s, 0); !ok {
+		return fmt.Errorf("cannot decode %q into *big.Int", s)
+	}
+	return nil
+}
+
+func unmarshalBigFloat(v *big.Float, b []byte) error {
+	s := bigNumberLiteral(b)
+	// Use enough mantissa bits to represent every decimal digit of the literal
+	prec := uint(math.Ceil(float64(len(s)) * math.Log2(10)))
+	if prec < 64 {
+		prec = 64
+	}
+	f, _, err := big.ParseFloat(s, 10, prec, big.ToNearestEven)
+	if err != nil {
+		return fmt.Errorf("cannot decode %q into *big.Float: %w", s, err)
+	}
+	v.Set(f)
+	return nil
+}
+
+func unmarshalBigRat(v *big.Rat, b []byte) error {
+	s := bigNumberLiteral(b)
+	if _, ok := v.SetString(s); !ok {
+		return fmt.Errorf("cannot decode %q into *big.Rat", s)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package yamlformat
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAllowIntegerStrings(t *testing.T) {
+	type record struct {
+		ID    int64   `json:"id"`
+		Count uint64  `json:"count"`
+		Small int8    `json:"small"`
+		IDs   []int64 `json:"ids"`
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    record
+		wantErr bool
+	}{
+		{
+			name:  "strings",
+			input: `{"id": "-9007199254740993", "count": "18446744073709551615", "small": "-128", "ids": ["1", 2]}`,
+			want:  record{ID: -9007199254740993, Count: 18446744073709551615, Small: -128, IDs: []int64{1, 2}},
+		},
+		{
+			name:  "numbers",
+			input: `{"id": 9007199254740993, "count": 1, "small": 127}`,
+			want:  record{ID: 9007199254740993, Count: 1, Small: 127},
+		},
+		{
+			name:  "YAML single quotes and hex",
+			input: "id: '42'\ncount: 0x10\n",
+			want:  record{ID: 42, Count: 16},
+		},
+		{
+			name:    "not a number",
+			input:   `{"id": "abc"}`,
+			wantErr: true,
+		},
+		{
+			name:    "out of range",
+			input:   `{"small": "128"}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got record
+			err := Unmarshal([]byte(tt.input), &got, AllowIntegerStrings())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAllowIntegerStringsRoundTrip(t *testing.T) {
+	type record struct {
+		ID   int64  `json:"id"`
+		Size uint64 `json:"size"`
+	}
+	want := record{ID: 1<<62 + 1, Size: 1<<64 - 1}
+
+	data, err := MarshalJSON(want, JSONInt64Strings(Int64AsString))
+	if err != nil {
+		t.Fatalf("MarshalJSON failed: %v", err)
+	}
+
+	var got record
+	if err := UnmarshalJSON(data, &got, AllowIntegerStrings()); err != nil {
+		t.Fatalf("UnmarshalJSON failed: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("round trip mismatch (-want +got):\n%s", diff)
+	}
+}
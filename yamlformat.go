@@ -3,8 +3,11 @@
 package yamlformat
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
+	"reflect"
 	"strings"
 
 	"github.com/goccy/go-yaml"
@@ -16,108 +19,385 @@ type Format string
 const (
 	FormatYAML Format = "yaml"
 	FormatJSON Format = "json"
+	// FormatJSONPretty is JSON indented with two spaces, one key or element per line
+	FormatJSONPretty Format = "json-pretty"
+	// FormatJSONL is JSON Lines (also known as NDJSON): one JSON value per line
+	FormatJSONL Format = "jsonl"
 )
 
-// Common options for consistent behavior
-var (
-	// marshalOptions are the default options for marshaling
-	marshalOptions = []yaml.EncodeOption{
-		yaml.UseJSONMarshaler(),
-		yaml.AutoInt(),
-		yaml.UseLiteralStyleIfMultiline(true),
+// prettyJSONIndent is the indentation used by FormatJSONPretty
+const prettyJSONIndent = "  "
+
+// Common options for consistent behavior.
+// defaultOptions lists the default options by name in the order they are applied;
+// Options.Without removes entries from it.
+var defaultOptions = []defaultOption{
+	{DefaultJSONMarshaler, yaml.UseJSONMarshaler(), yaml.UseJSONUnmarshaler()},
+	{DefaultAutoInt, yaml.AutoInt(), nil},
+	{DefaultLiteralMultiline, yaml.UseLiteralStyleIfMultiline(true), nil},
+	{DefaultFloatFormatter, combineEncodeOptions(
+		yaml.CustomMarshaler[float32](marshalFloat32),
 		yaml.CustomMarshaler[float64](marshalFloat64),
-	}
-	
-	// unmarshalOptions are the default options for unmarshaling
-	unmarshalOptions = []yaml.DecodeOption{
-		yaml.UseJSONUnmarshaler(),
-	}
-)
+	), nil},
+	{DefaultBigNumbers, combineEncodeOptions(
+		bigNumberMarshalers(),
+		yaml.CustomMarshaler[json.Number](marshalJSONNumber),
+	), bigNumberUnmarshalers()},
+}
 
-// IsValid checks if the format is supported
+// IsValid checks if the format is supported, that is, registered
 func (f Format) IsValid() bool {
-	return f == FormatYAML || f == FormatJSON
+	_, ok := registry.lookup(f)
+	return ok
+}
+
+// UnknownFormatError reports a format that is not registered
+type UnknownFormatError struct {
+	// Name is the format name as given
+	Name string
+	// Valid lists the registered formats
+	Valid []Format
+}
+
+func (e *UnknownFormatError) Error() string {
+	return fmt.Sprintf("invalid format: %s (valid: %s)", e.Name, formatList(e.Valid))
+}
+
+// Validate returns a *UnknownFormatError if the format is not registered
+func (f Format) Validate() error {
+	if !f.IsValid() {
+		return &UnknownFormatError{Name: string(f), Valid: Formats()}
+	}
+	return nil
+}
+
+// spec returns the registered spec of the format, or the YAML spec if the format is not registered
+func (f Format) spec() *FormatSpec {
+	if spec, ok := registry.lookup(f); ok {
+		return spec
+	}
+	spec, _ := registry.lookup(FormatYAML) // Default to YAML
+	return spec
 }
 
 // Marshal marshals data to bytes in this format
 func (f Format) Marshal(v interface{}, opts ...yaml.EncodeOption) ([]byte, error) {
-	switch f {
-	case FormatJSON:
-		return MarshalJSON(v, opts...)
-	case FormatYAML:
-		return Marshal(v, opts...)
-	default:
-		return Marshal(v, opts...) // Default to YAML
-	}
+	return f.spec().Marshal(v, opts...)
 }
 
-// NewEncoder creates a new encoder for this format
+// NewEncoder creates a new encoder for this format.
+// Like NewEncoder, it returns a yaml.Encoder; NewStreamEncoder returns an Encoder without its limitations.
+// For a format registered by another package, each value is encoded to YAML by yaml.Encoder,
+// decoded into interface{} and written by the Marshal function of the format.
 func (f Format) NewEncoder(w io.Writer, opts ...yaml.EncodeOption) *yaml.Encoder {
-	switch f {
-	case FormatJSON:
-		return NewJSONEncoder(w, opts...)
-	case FormatYAML:
-		return NewEncoder(w, opts...)
-	default:
-		return NewEncoder(w, opts...) // Default to YAML
+	spec := f.spec()
+	if spec.newYAMLEncoder != nil {
+		return spec.newYAMLEncoder(w, opts...)
+	}
+	return yamlEncodeConfig(opts).newEncoder(w, func(doc []byte) ([]byte, error) {
+		var v interface{}
+		if err := Unmarshal(doc, &v); err != nil {
+			return nil, err
+		}
+		return spec.Marshal(v, opts...)
+	})
+}
+
+// MarshalStrict is like Marshal but returns a *UnknownFormatError instead of defaulting to YAML
+// if the format is not registered
+func (f Format) MarshalStrict(v interface{}, opts ...yaml.EncodeOption) ([]byte, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	return f.Marshal(v, opts...)
+}
+
+// NewEncoderStrict is like NewEncoder but returns a *UnknownFormatError instead of defaulting to YAML
+// if the format is not registered
+func (f Format) NewEncoderStrict(w io.Writer, opts ...yaml.EncodeOption) (*yaml.Encoder, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	return f.NewEncoder(w, opts...), nil
+}
+
+// Unmarshal unmarshals bytes in this format into v
+func (f Format) Unmarshal(data []byte, v interface{}, opts ...yaml.DecodeOption) error {
+	return f.spec().Unmarshal(data, v, opts...)
+}
+
+// NewDecoder creates a new decoder for this format, e.g. a *yaml.Decoder for FormatYAML
+func (f Format) NewDecoder(r io.Reader, opts ...yaml.DecodeOption) ValueDecoder {
+	return f.spec().NewDecoder(r, opts...)
+}
+
+// UnmarshalStrict is like Unmarshal but returns a *UnknownFormatError instead of defaulting to YAML
+// if the format is not registered
+func (f Format) UnmarshalStrict(data []byte, v interface{}, opts ...yaml.DecodeOption) error {
+	if err := f.Validate(); err != nil {
+		return err
 	}
+	return f.Unmarshal(data, v, opts...)
 }
 
-// ParseFormat parses a string into a Format
+// NewDecoderStrict is like NewDecoder but returns a *UnknownFormatError instead of defaulting to YAML
+// if the format is not registered
+func (f Format) NewDecoderStrict(r io.Reader, opts ...yaml.DecodeOption) (ValueDecoder, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	return f.NewDecoder(r, opts...), nil
+}
+
+// ParseFormat parses a string into a Format.
+// It accepts the names and aliases of the registered formats, case-insensitively,
+// and returns a *UnknownFormatError for any other string.
 func ParseFormat(s string) (Format, error) {
-	format := Format(strings.ToLower(s))
-	if !format.IsValid() {
-		return "", fmt.Errorf("invalid format: %s (valid: yaml, json)", s)
+	format, ok := registry.parse(s)
+	if !ok {
+		return "", &UnknownFormatError{Name: s, Valid: Formats()}
 	}
 	return format, nil
 }
 
+// String returns the format name
+func (f Format) String() string {
+	return string(f)
+}
+
+// Set parses s with ParseFormat and stores the result, implementing flag.Value
+func (f *Format) Set(s string) error {
+	format, err := ParseFormat(s)
+	if err != nil {
+		return err
+	}
+	*f = format
+	return nil
+}
+
+// Type returns the type name shown in the help text of pflag-style flag packages
+func (f *Format) Type() string {
+	return "format"
+}
+
+// MarshalText implements encoding.TextMarshaler.
+// It returns a *UnknownFormatError if the format is neither empty nor registered.
+func (f Format) MarshalText() ([]byte, error) {
+	if f == "" {
+		return nil, nil
+	}
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	return []byte(f), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler using ParseFormat, so aliases are accepted.
+// Empty text is the zero Format, which MarshalText writes as empty text.
+func (f *Format) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*f = ""
+		return nil
+	}
+	return f.Set(string(text))
+}
+
+// FormatVar defines a Format flag with the specified name, default value and usage in fs.
+// The valid formats are appended to usage.
+func FormatVar(fs *flag.FlagSet, p *Format, name string, value Format, usage string) {
+	*p = value
+	fs.Var(p, name, FormatUsage(usage))
+}
+
+// FormatUsage appends the registered formats to usage for the help text of a format flag
+func FormatUsage(usage string) string {
+	return fmt.Sprintf("%s (one of: %s)", usage, formatList(Formats()))
+}
+
+// formatList joins format names for error messages
+func formatList(formats []Format) string {
+	names := make([]string, len(formats))
+	for i, f := range formats {
+		names[i] = string(f)
+	}
+	return strings.Join(names, ", ")
+}
+
 // Marshal marshals data to YAML bytes using consistent options
 func Marshal(v interface{}, opts ...yaml.EncodeOption) ([]byte, error) {
-	allOpts := append([]yaml.EncodeOption{}, marshalOptions...)
-	allOpts = append(allOpts, opts...)
-	return yaml.MarshalWithOptions(v, allOpts...)
+	c := yamlEncodeConfig(opts)
+	b, err := c.marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if prefix := c.settings.documentPrefix(); prefix != "" {
+		b = append([]byte(prefix), b...)
+	}
+	return append(b, c.settings.documentSuffix()...), nil
 }
 
 // MarshalJSON marshals data to JSON bytes
 func MarshalJSON(v interface{}, opts ...yaml.EncodeOption) ([]byte, error) {
-	allOpts := append([]yaml.EncodeOption{}, marshalOptions...)
-	allOpts = append(allOpts, yaml.JSON())
-	allOpts = append(allOpts, opts...)
-	return yaml.MarshalWithOptions(v, allOpts...)
+	c := jsonEncodeConfig(opts)
+	b, err := c.marshal(v)
+	if rewrite := c.jsonRewriter(c.settings.jsonLayout, reflect.ValueOf(v)); err == nil && rewrite != nil {
+		b, err = rewrite(b)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if header := c.settings.jsonHeader(); header != "" {
+		b = append([]byte(header), b...)
+	}
+	return append(b, c.settings.jsonFooter()...), nil
+}
+
+// MarshalJSONCompact marshals data to compact JSON bytes like encoding/json.Marshal:
+// no insignificant whitespace, no trailing newline, and the same string escaping.
+// Structs, maps and []byte are written by the rules of encoding/json: field names from json tags or
+// the Go names, promoted fields of embedded structs, omitempty, omitzero and the string option,
+// maps sorted by key, and []byte as base64.
+// Values with a MarshalJSON or MarshalText method are written with the compacted result of the method,
+// and invalid UTF-8 in strings is replaced by U+FFFD, as by encoding/json.
+// Other numbers keep the package's formatting (whole floats as integers, no scientific notation),
+// and values with only YAML marshaler methods are written like MarshalJSON writes them.
+func MarshalJSONCompact(v interface{}, opts ...yaml.EncodeOption) ([]byte, error) {
+	v, err := loadEncodeSettings(opts).jsonCompatible(v)
+	if err != nil {
+		return nil, err
+	}
+	b, err := MarshalJSON(v, append(opts[:len(opts):len(opts)], jsonComments(false))...)
+	if err != nil {
+		return nil, err
+	}
+	jw := &jsonNodeWriter{raw: true}
+	return jw.format(b)
+}
+
+// MarshalJSONIndent is like MarshalJSON but applies indentation like encoding/json.MarshalIndent.
+// Each key or element begins on a new line starting with prefix followed by
+// copies of indent according to the nesting depth.
+func MarshalJSONIndent(v interface{}, prefix, indent string, opts ...yaml.EncodeOption) ([]byte, error) {
+	return MarshalJSON(v, append(opts[:len(opts):len(opts)], jsonIndent(prefix, indent))...)
+}
+
+// MarshalJSONL marshals data to a single JSON Lines record:
+// compact JSON terminated by a newline
+func MarshalJSONL(v interface{}, opts ...yaml.EncodeOption) ([]byte, error) {
+	b, err := MarshalJSON(v, append(opts[:len(opts):len(opts)], jsonComments(false))...)
+	if err != nil {
+		return nil, err
+	}
+	return compactJSONLine(b)
 }
 
 // Unmarshal unmarshals YAML/JSON bytes using consistent options
 func Unmarshal(data []byte, v interface{}, opts ...yaml.DecodeOption) error {
-	allOpts := append([]yaml.DecodeOption{}, unmarshalOptions...)
-	allOpts = append(allOpts, opts...)
-	return yaml.UnmarshalWithOptions(data, v, allOpts...)
+	return yaml.UnmarshalWithOptions(data, v, allUnmarshalOptions(opts)...)
+}
+
+// UnmarshalJSON unmarshals strict JSON bytes using consistent options.
+// Unlike Unmarshal, it rejects input that is valid YAML but not valid JSON.
+// A repeated object key takes the last value, as in encoding/json.
+func UnmarshalJSON(data []byte, v interface{}, opts ...yaml.DecodeOption) error {
+	if err := validateJSON(data); err != nil {
+		return err
+	}
+	return Unmarshal(data, v, jsonDecodeOptions(opts)...)
+}
+
+// UnmarshalJSONL unmarshals a single JSON Lines record using consistent options.
+// Use NewJSONLDecoder to read multiple records.
+func UnmarshalJSONL(data []byte, v interface{}, opts ...yaml.DecodeOption) error {
+	records, err := splitJSONLines(data)
+	if err != nil {
+		return err
+	}
+	if len(records) != 1 {
+		return fmt.Errorf("invalid JSON Lines: want exactly one record, got %d", len(records))
+	}
+	return Unmarshal(records[0], v, jsonDecodeOptions(opts)...)
 }
 
-// NewEncoder creates a new YAML encoder with consistent options
+// NewEncoder creates a new YAML encoder with consistent options.
+// Each call to Encode writes one document; documents are separated by "---".
+// yaml.Encoder encodes the values itself, so unlike Marshal the key order treats struct fields like map keys,
+// struct tag comments, big numbers in interface{} values and the footer comment are not written,
+// and the errors of w are not reported. NewStreamEncoder returns an Encoder without these limitations.
 func NewEncoder(w io.Writer, opts ...yaml.EncodeOption) *yaml.Encoder {
-	allOpts := append([]yaml.EncodeOption{}, marshalOptions...)
-	allOpts = append(allOpts, opts...)
-	return yaml.NewEncoder(w, allOpts...)
+	c := yamlEncodeConfig(opts)
+	if prefix := c.settings.documentPrefix(); prefix != "" {
+		w = &documentStartWriter{w: w, header: commentText(c.settings.header, "#"), marker: c.settings.documentStart}
+	}
+	return c.newEncoder(w, nil)
 }
 
-// NewJSONEncoder creates a new JSON encoder with consistent options
+// NewJSONEncoder creates a new JSON encoder with consistent options.
+// Each call to Encode writes one JSON value; it has the limitations of NewEncoder.
 func NewJSONEncoder(w io.Writer, opts ...yaml.EncodeOption) *yaml.Encoder {
-	allOpts := append([]yaml.EncodeOption{}, marshalOptions...)
-	allOpts = append(allOpts, yaml.JSON())
-	allOpts = append(allOpts, opts...)
-	return yaml.NewEncoder(w, allOpts...)
+	c := jsonEncodeConfig(opts)
+	if header := c.settings.jsonHeader(); header != "" {
+		w = &documentStartWriter{w: w, header: header}
+	}
+	rewrite := c.jsonRewriter(c.settings.jsonLayout, reflect.Value{})
+	if rewrite == nil {
+		// Drop the "---" separators, which are not valid JSON
+		rewrite = func(doc []byte) ([]byte, error) { return doc, nil }
+	}
+	return c.newEncoder(w, rewrite)
+}
+
+// NewJSONCompactEncoder creates a new JSON encoder whose output is like that of encoding/json.Encoder:
+// each call to Encode writes the value like MarshalJSONCompact followed by a newline.
+func NewJSONCompactEncoder(w io.Writer, opts ...yaml.EncodeOption) *Encoder {
+	return newValueEncoder(w, &streamLayout{element: func(v interface{}) ([]byte, error) {
+		b, err := MarshalJSONCompact(v, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return append(b, '\n'), nil
+	}})
+}
+
+// NewJSONIndentEncoder creates a new JSON encoder that indents its output like MarshalJSONIndent
+func NewJSONIndentEncoder(w io.Writer, prefix, indent string, opts ...yaml.EncodeOption) *Encoder {
+	return newValueEncoder(w, jsonDocumentsLayout(opts, func(v interface{}, opts ...yaml.EncodeOption) ([]byte, error) {
+		return MarshalJSONIndent(v, prefix, indent, opts...)
+	}))
+}
+
+// NewJSONLEncoder creates a new JSON Lines encoder with consistent options.
+// Each call to Encode writes one compact JSON value followed by a newline, like MarshalJSONL.
+func NewJSONLEncoder(w io.Writer, opts ...yaml.EncodeOption) *Encoder {
+	return newValueEncoder(w, newDocumentsLayout(FormatJSONL, opts))
+}
+
+// newJSONLinesEncoder creates a yaml.Encoder that writes JSON Lines, for FormatJSONL.NewEncoder
+func newJSONLinesEncoder(w io.Writer, opts ...yaml.EncodeOption) *yaml.Encoder {
+	return jsonEncodeConfig(opts).newEncoder(w, compactJSONLine)
+}
+
+// NewDecoder creates a new YAML decoder with consistent options
+func NewDecoder(r io.Reader, opts ...yaml.DecodeOption) *yaml.Decoder {
+	return yaml.NewDecoder(r, allUnmarshalOptions(opts)...)
+}
+
+// NewJSONDecoder creates a new JSON decoder with consistent options.
+// The input is a stream of strict JSON values, and each call to Decode
+// reads and decodes the next value. Input that is not valid JSON is rejected.
+// A repeated object key takes the last value, as in encoding/json.
+func NewJSONDecoder(r io.Reader, opts ...yaml.DecodeOption) *Decoder {
+	return &Decoder{next: nextJSONValueFunc(r), opts: jsonDecodeOptions(opts)}
+}
+
+// NewJSONLDecoder creates a new JSON Lines decoder with consistent options.
+// Each call to Decode reads and decodes the next record. Blank lines are skipped.
+func NewJSONLDecoder(r io.Reader, opts ...yaml.DecodeOption) *Decoder {
+	return &Decoder{next: nextJSONLineFunc(r), opts: jsonDecodeOptions(opts)}
 }
 
 // NewEncoderForFormat creates a new encoder for the specified format
-// Deprecated: Use Format.NewEncoder instead
+// Deprecated: Use Format.NewEncoder, or Format.NewEncoderStrict to reject unknown formats, instead
 func NewEncoderForFormat(w io.Writer, format Format) *yaml.Encoder {
-	switch format {
-	case FormatJSON:
-		return NewJSONEncoder(w)
-	case FormatYAML:
-		return NewEncoder(w)
-	default:
-		return NewEncoder(w) // Default to YAML
-	}
+	return format.NewEncoder(w)
 }
\ No newline at end of file
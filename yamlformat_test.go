@@ -2,8 +2,14 @@ package yamlformat
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/goccy/go-yaml"
 	"github.com/google/go-cmp/cmp"
@@ -124,6 +130,89 @@ func TestMarshalJSON(t *testing.T) {
 	}
 }
 
+func TestMarshalJSONIndent(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  interface{}
+		prefix string
+		indent string
+		want   string
+	}{
+		{
+			name: "nested structure with sorted keys",
+			input: map[string]interface{}{
+				"name":  "test",
+				"items": []interface{}{1, 2.5},
+				"user":  map[string]interface{}{"id": 123},
+				"empty": map[string]interface{}{},
+			},
+			indent: "  ",
+			want: `{
+  "empty": {},
+  "items": [
+    1,
+    2.5
+  ],
+  "name": "test",
+  "user": {
+    "id": 123
+  }
+}
+`,
+		},
+		{
+			name: "struct field order is preserved",
+			input: struct {
+				Zeta  string `json:"zeta"`
+				Alpha int    `json:"alpha"`
+			}{Zeta: "z", Alpha: 1},
+			indent: "\t",
+			want:   "{\n\t\"zeta\": \"z\",\n\t\"alpha\": 1\n}\n",
+		},
+		{
+			name:   "prefix",
+			input:  []string{"a"},
+			prefix: "> ",
+			indent: "  ",
+			want:   "[\n>   \"a\"\n> ]\n",
+		},
+		{
+			name:   "scalar",
+			input:  "text",
+			indent: "  ",
+			want:   "\"text\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MarshalJSONIndent(tt.input, tt.prefix, tt.indent)
+			if err != nil {
+				t.Fatalf("MarshalJSONIndent failed: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("MarshalJSONIndent() = %q, want %q", string(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestNewJSONIndentEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	encoder := NewJSONIndentEncoder(&buf, "", "  ")
+
+	for _, v := range []interface{}{map[string]interface{}{"a": 1}, []int{2}} {
+		if err := encoder.Encode(v); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+
+	want := "{\n  \"a\": 1\n}\n[\n  2\n]\n"
+	if buf.String() != want {
+		t.Errorf("Encode() = %q, want %q", buf.String(), want)
+	}
+}
+
 func TestUnmarshal(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -244,6 +333,18 @@ func TestFormatMarshal(t *testing.T) {
 			input:  map[string]interface{}{"key": "value"},
 			want:   `{"key": "value"}` + "\n",
 		},
+		{
+			name:   "JSON pretty format",
+			format: FormatJSONPretty,
+			input:  map[string]interface{}{"key": "value"},
+			want:   "{\n  \"key\": \"value\"\n}\n",
+		},
+		{
+			name:   "JSONL format",
+			format: FormatJSONL,
+			input:  map[string]interface{}{"key": "value"},
+			want:   `{"key":"value"}` + "\n",
+		},
 		{
 			name:   "invalid format defaults to YAML",
 			format: Format("invalid"),
@@ -285,6 +386,12 @@ func TestFormatNewEncoder(t *testing.T) {
 			input:  map[string]interface{}{"key": "value"},
 			want:   `{"key": "value"}`,
 		},
+		{
+			name:   "JSONL format",
+			format: FormatJSONL,
+			input:  map[string]interface{}{"key": "value"},
+			want:   `{"key":"value"}`,
+		},
 		{
 			name:   "invalid format defaults to YAML",
 			format: Format("invalid"),
@@ -385,6 +492,26 @@ func TestParseFormat(t *testing.T) {
 			input: "JSON",
 			want:  FormatJSON,
 		},
+		{
+			name:  "jsonl lowercase",
+			input: "jsonl",
+			want:  FormatJSONL,
+		},
+		{
+			name:  "json-pretty",
+			input: "json-pretty",
+			want:  FormatJSONPretty,
+		},
+		{
+			name:  "ndjson alias",
+			input: "NDJSON",
+			want:  FormatJSONL,
+		},
+		{
+			name:  "yml alias",
+			input: "yml",
+			want:  FormatYAML,
+		},
 		{
 			name:    "invalid format",
 			input:   "xml",
@@ -407,3 +534,520 @@ func TestParseFormat(t *testing.T) {
 }
 
 
+func TestFormatUnmarshal(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  Format
+		input   string
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name:   "YAML format with YAML input",
+			format: FormatYAML,
+			input:  "key: value\n",
+			want:   map[string]interface{}{"key": "value"},
+		},
+		{
+			name:   "YAML format with JSON input",
+			format: FormatYAML,
+			input:  `{"key": "value"}`,
+			want:   map[string]interface{}{"key": "value"},
+		},
+		{
+			name:   "JSON format with JSON input",
+			format: FormatJSON,
+			input:  `{"key": "value", "count": 42}`,
+			want:   map[string]interface{}{"key": "value", "count": uint64(42)},
+		},
+		{
+			name:    "JSON format rejects YAML input",
+			format:  FormatJSON,
+			input:   "key: value\n",
+			wantErr: true,
+		},
+		{
+			name:    "JSON format rejects unquoted keys",
+			format:  FormatJSON,
+			input:   `{key: "value"}`,
+			wantErr: true,
+		},
+		{
+			name:    "JSON format rejects multiple values",
+			format:  FormatJSON,
+			input:   `{"key": "value"} {"key": "value"}`,
+			wantErr: true,
+		},
+		{
+			name:   "JSON format takes the last of duplicate keys",
+			format: FormatJSON,
+			input:  `{"key": "a", "key": "b"}`,
+			want:   map[string]interface{}{"key": "b"},
+		},
+		{
+			name:   "JSON format decodes every number as a number",
+			format: FormatJSON,
+			input:  `{"exp": 1e3, "huge": 123456789012345678901234567890, "s": "1e3"}`,
+			want:   map[string]interface{}{"exp": 1000.0, "huge": 1.2345678901234568e+29, "s": "1e3"},
+		},
+		{
+			name:    "YAML format rejects duplicate keys",
+			format:  FormatYAML,
+			input:   "key: a\nkey: b\n",
+			wantErr: true,
+		},
+		{
+			name:   "invalid format defaults to YAML",
+			format: Format("invalid"),
+			input:  "key: value\n",
+			want:   map[string]interface{}{"key": "value"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got map[string]interface{}
+			err := tt.format.Unmarshal([]byte(tt.input), &got)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr {
+				if diff := cmp.Diff(tt.want, got); diff != "" {
+					t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatNewDecoder(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  Format
+		input   string
+		want    []map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name:   "YAML documents",
+			format: FormatYAML,
+			input:  "key: a\n---\nkey: b\n",
+			want: []map[string]interface{}{
+				{"key": "a"},
+				{"key": "b"},
+			},
+		},
+		{
+			name:   "JSON values",
+			format: FormatJSON,
+			input:  "{\"key\": \"a\"}\n{\"key\": \"b\"} {\"key\": \"c\"}",
+			want: []map[string]interface{}{
+				{"key": "a"},
+				{"key": "b"},
+				{"key": "c"},
+			},
+		},
+		{
+			name:   "JSON values with duplicate keys",
+			format: FormatJSON,
+			input:  `{"key": "a", "key": "b"} {"key": "c"}`,
+			want: []map[string]interface{}{
+				{"key": "b"},
+				{"key": "c"},
+			},
+		},
+		{
+			name:   "JSON exponents",
+			format: FormatJSON,
+			input:  `{"key": 1e3} {"key": -2E-2}`,
+			want: []map[string]interface{}{
+				{"key": 1000.0},
+				{"key": -0.02},
+			},
+		},
+		{
+			name:   "empty JSON input",
+			format: FormatJSON,
+			input:  "",
+		},
+		{
+			name:    "JSON format rejects YAML input",
+			format:  FormatJSON,
+			input:   "key: a\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := tt.format.NewDecoder(strings.NewReader(tt.input))
+
+			var got []map[string]interface{}
+			for {
+				var v map[string]interface{}
+				err := decoder.Decode(&v)
+				if err == io.EOF {
+					break
+				}
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("Decode() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if err != nil {
+					return
+				}
+				got = append(got, v)
+			}
+			if tt.wantErr {
+				t.Fatal("Decode() succeeded, want error")
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewJSONLEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	encoder := NewJSONLEncoder(&buf)
+
+	records := []interface{}{
+		map[string]interface{}{"name": "a", "tags": []string{"x", "y"}},
+		map[string]interface{}{"name": "multi\nline"},
+		"scalar",
+		nil,
+	}
+	for _, r := range records {
+		if err := encoder.Encode(r); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+
+	want := `{"name":"a","tags":["x","y"]}` + "\n" +
+		`{"name":"multi\nline"}` + "\n" +
+		`"scalar"` + "\n" +
+		"null\n"
+	if buf.String() != want {
+		t.Errorf("Encode() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestNewJSONLDecoder(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []interface{}
+		wantErr string
+	}{
+		{
+			name:  "records with blank lines",
+			input: "{\"id\": 1}\n\n[1, 2]\r\n\"text\"\n",
+			want: []interface{}{
+				map[string]interface{}{"id": uint64(1)},
+				[]interface{}{uint64(1), uint64(2)},
+				"text",
+			},
+		},
+		{
+			name:  "missing trailing newline",
+			input: "{\"id\": 1}\n{\"id\": 2}",
+			want: []interface{}{
+				map[string]interface{}{"id": uint64(1)},
+				map[string]interface{}{"id": uint64(2)},
+			},
+		},
+		{
+			name:    "value spanning lines",
+			input:   "{\"id\": 1}\n{\n\"id\": 2}\n",
+			wantErr: "line 2: invalid JSON",
+		},
+		{
+			name:    "YAML record",
+			input:   "{\"id\": 1}\nid: 2\n",
+			wantErr: "line 2: invalid JSON",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := FormatJSONL.NewDecoder(strings.NewReader(tt.input))
+
+			var got []interface{}
+			for {
+				var v interface{}
+				err := decoder.Decode(&v)
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					if tt.wantErr == "" || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Fatalf("Decode() error = %v, want %q", err, tt.wantErr)
+					}
+					return
+				}
+				got = append(got, v)
+			}
+			if tt.wantErr != "" {
+				t.Fatalf("Decode() succeeded, want error %q", tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestJSONDecodersStream(t *testing.T) {
+	tests := []struct {
+		name       string
+		newDecoder func(r io.Reader) *Decoder
+	}{
+		{name: "NewJSONDecoder", newDecoder: func(r io.Reader) *Decoder { return NewJSONDecoder(r) }},
+		{name: "NewJSONLDecoder", newDecoder: func(r io.Reader) *Decoder { return NewJSONLDecoder(r) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pr, pw := io.Pipe()
+			defer pw.Close()
+			dec := tt.newDecoder(pr)
+
+			// Each record is decoded while the writer is still open
+			for i := 1; i <= 2; i++ {
+				go fmt.Fprintf(pw, "{\"id\": %d}\n", i)
+				decoded := make(chan error, 1)
+				var v struct {
+					ID int `yaml:"id"`
+				}
+				go func() { decoded <- dec.Decode(&v) }()
+				select {
+				case err := <-decoded:
+					if err != nil {
+						t.Fatalf("Decode failed: %v", err)
+					}
+				case <-time.After(5 * time.Second):
+					t.Fatalf("Decode of record %d did not return before the writer was closed", i)
+				}
+				if v.ID != i {
+					t.Errorf("Decode() id = %d, want %d", v.ID, i)
+				}
+			}
+
+			pw.Close()
+			var v interface{}
+			if err := dec.Decode(&v); err != io.EOF {
+				t.Errorf("Decode() after the last record error = %v, want io.EOF", err)
+			}
+		})
+	}
+}
+
+func TestUnmarshalJSONL(t *testing.T) {
+	var got map[string]interface{}
+	if err := UnmarshalJSONL([]byte("{\"id\": 1}\n"), &got); err != nil {
+		t.Fatalf("UnmarshalJSONL failed: %v", err)
+	}
+	if diff := cmp.Diff(map[string]interface{}{"id": uint64(1)}, got); diff != "" {
+		t.Errorf("UnmarshalJSONL() mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := UnmarshalJSONL([]byte("{\"id\": 1}\n{\"id\": 2}\n"), &got); err == nil {
+		t.Error("UnmarshalJSONL() with two records succeeded, want error")
+	}
+}
+
+func TestUnmarshalJSONDuplicateKeys(t *testing.T) {
+	// As in encoding/json, the last value of a repeated key takes effect, also with the package decoders
+	const input = `{"b": 1, "a": {"x": 1}, "b": 2, "a": {"y": 2}}`
+	tests := []struct {
+		name string
+		opts []yaml.DecodeOption
+		want interface{}
+	}{
+		{name: "default", want: map[string]interface{}{"a": map[string]interface{}{"y": uint64(2)}, "b": uint64(2)}},
+		{
+			name: "DecodeNumbers",
+			opts: []yaml.DecodeOption{DecodeNumbers(NumberJSONNumber)},
+			want: map[string]interface{}{"a": map[string]interface{}{"y": json.Number("2")}, "b": json.Number("2")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got map[string]interface{}
+			if err := UnmarshalJSON([]byte(input), &got, tt.opts...); err != nil {
+				t.Fatalf("UnmarshalJSON failed: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("UnmarshalJSON() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	var m OrderedMap
+	if err := UnmarshalJSONL([]byte(input), &m); err != nil {
+		t.Fatalf("UnmarshalJSONL failed: %v", err)
+	}
+	if diff := cmp.Diff([]string{"b", "a"}, m.Keys()); diff != "" {
+		t.Errorf("OrderedMap keys mismatch (-want +got):\n%s", diff)
+	}
+	if v, _ := m.Get("b"); v != uint64(2) {
+		t.Errorf("OrderedMap b = %v, want 2", v)
+	}
+
+	if err := Unmarshal([]byte(input), &m); err == nil {
+		t.Error("Unmarshal() with duplicate keys succeeded, want error")
+	}
+}
+
+func TestFormatStrict(t *testing.T) {
+	wantErr := func(t *testing.T, err error) {
+		t.Helper()
+		var unknown *UnknownFormatError
+		if !errors.As(err, &unknown) {
+			t.Fatalf("error = %v, want *UnknownFormatError", err)
+		}
+		if unknown.Name != "xml" {
+			t.Errorf("UnknownFormatError.Name = %q, want %q", unknown.Name, "xml")
+		}
+		if diff := cmp.Diff(Formats(), unknown.Valid); diff != "" {
+			t.Errorf("UnknownFormatError.Valid mismatch (-want +got):\n%s", diff)
+		}
+	}
+	xml := Format("xml")
+
+	t.Run("Validate", func(t *testing.T) {
+		wantErr(t, xml.Validate())
+		if err := FormatJSONL.Validate(); err != nil {
+			t.Errorf("Validate() = %v, want nil", err)
+		}
+	})
+	t.Run("MarshalStrict", func(t *testing.T) {
+		_, err := xml.MarshalStrict(map[string]int{"a": 1})
+		wantErr(t, err)
+		got, err := FormatJSON.MarshalStrict(map[string]int{"a": 1})
+		if err != nil {
+			t.Fatalf("MarshalStrict failed: %v", err)
+		}
+		if want := "{\"a\": 1}\n"; string(got) != want {
+			t.Errorf("MarshalStrict() = %q, want %q", got, want)
+		}
+	})
+	t.Run("NewEncoderStrict", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc, err := xml.NewEncoderStrict(&buf)
+		wantErr(t, err)
+		if enc != nil {
+			t.Errorf("NewEncoderStrict() returned an encoder with an error")
+		}
+		if enc, err = FormatYAML.NewEncoderStrict(&buf); err != nil || enc == nil {
+			t.Errorf("NewEncoderStrict() = %v, %v, want an encoder", enc, err)
+		}
+	})
+	t.Run("UnmarshalStrict", func(t *testing.T) {
+		var v map[string]int
+		wantErr(t, xml.UnmarshalStrict([]byte("a: 1"), &v))
+		if err := FormatYAML.UnmarshalStrict([]byte("a: 1"), &v); err != nil {
+			t.Errorf("UnmarshalStrict() = %v", err)
+		}
+	})
+	t.Run("NewDecoderStrict", func(t *testing.T) {
+		_, err := xml.NewDecoderStrict(strings.NewReader("a: 1"))
+		wantErr(t, err)
+		if dec, err := FormatJSON.NewDecoderStrict(strings.NewReader("{}")); err != nil || dec == nil {
+			t.Errorf("NewDecoderStrict() = %v, %v, want a decoder", dec, err)
+		}
+	})
+	t.Run("ParseFormat", func(t *testing.T) {
+		_, err := ParseFormat("xml")
+		wantErr(t, err)
+	})
+}
+
+func TestFormatFlag(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	var format Format
+	FormatVar(fs, &format, "format", FormatYAML, "output format")
+
+	if got, want := fs.Lookup("format").Usage, "output format (one of: yaml, json, json-pretty, jsonl)"; got != want {
+		t.Errorf("Usage = %q, want %q", got, want)
+	}
+	if got := fs.Lookup("format").DefValue; got != "yaml" {
+		t.Errorf("DefValue = %q, want %q", got, "yaml")
+	}
+	if err := fs.Parse([]string{"--format=NDJSON"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if format != FormatJSONL {
+		t.Errorf("format = %v, want %v", format, FormatJSONL)
+	}
+
+	err := fs.Parse([]string{"--format=xml"})
+	if err == nil || !strings.Contains(err.Error(), "invalid format: xml (valid: yaml, json, json-pretty, jsonl)") {
+		t.Errorf("Parse() error = %v, want the valid formats", err)
+	}
+	if format != FormatJSONL {
+		t.Errorf("format = %v after a failed Set, want %v", format, FormatJSONL)
+	}
+	if got := format.Type(); got != "format" {
+		t.Errorf("Type() = %q, want %q", got, "format")
+	}
+}
+
+func TestFormatText(t *testing.T) {
+	type config struct {
+		Input  Format `yaml:"input"`
+		Output Format `yaml:"output,omitempty"`
+	}
+
+	var c config
+	if err := Unmarshal([]byte("input: YML\noutput: ndjson"), &c); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if diff := cmp.Diff(config{Input: FormatYAML, Output: FormatJSONL}, c); diff != "" {
+		t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+	}
+	if err := Unmarshal([]byte("input: xml"), &c); err == nil {
+		t.Errorf("Unmarshal() with an unknown format succeeded, want error")
+	}
+
+	got, err := Marshal(config{Input: FormatJSON})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if want := "input: json\n"; string(got) != want {
+		t.Errorf("Marshal() = %q, want %q", got, want)
+	}
+	if _, err := Marshal(config{Input: "xml"}); err == nil {
+		t.Errorf("Marshal() with an unknown format succeeded, want error")
+	}
+
+	// The zero Format round-trips as empty text
+	zero := config{Output: FormatJSON}
+	for _, format := range []Format{FormatYAML, FormatJSON} {
+		data, err := format.Marshal(zero)
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		c = config{Input: FormatYAML}
+		if err := format.Unmarshal(data, &c); err != nil {
+			t.Fatalf("Unmarshal(%q) failed: %v", data, err)
+		}
+		if diff := cmp.Diff(zero, c); diff != "" {
+			t.Errorf("Unmarshal(%q) mismatch (-want +got):\n%s", data, diff)
+		}
+	}
+	data, err := json.Marshal(zero)
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+	c = config{Input: FormatYAML}
+	if err := json.Unmarshal(data, &c); err != nil {
+		t.Fatalf("json.Unmarshal(%q) failed: %v", data, err)
+	}
+	if diff := cmp.Diff(zero, c); diff != "" {
+		t.Errorf("json.Unmarshal(%q) mismatch (-want +got):\n%s", data, diff)
+	}
+}